@@ -4,6 +4,8 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -13,6 +15,141 @@ type Config struct {
 	BrowserName string            `yaml:"browser_name"`
 	Profiles    []Profile         `yaml:"profiles"`
 	Shortcuts   map[string]string `yaml:"shortcuts"`
+	Storage     Storage           `yaml:"storage"`
+
+	// ShortcutModes sets how shortcuts, local ones included, are opened
+	ShortcutModes map[string]LaunchMode `yaml:"shortcut_modes"`
+
+	// SearchEngines maps engine names to URL templates with a {q} placeholder
+	SearchEngines       map[string]string `yaml:"search_engines"`
+	DefaultSearchEngine string            `yaml:"default_search_engine"`
+
+	// Browsers override or extend the built-in browser definitions
+	Browsers map[string]Browser `yaml:"browsers"`
+
+	// Routes pick the profile of a URL, the first matching route wins
+	Routes         []Route `yaml:"routes"`
+	DefaultProfile string  `yaml:"default_profile"` // used when no route matches
+
+	Handler Handler `yaml:"handler"`
+
+	Menu Menu `yaml:"menu"`
+
+	Sessions map[string]Session `yaml:"sessions"`
+
+	// HTTPTimeout bounds the requests made to preview and check links
+	HTTPTimeout time.Duration `yaml:"http_timeout"`
+
+	// ConfirmAbove is the number of URLs read from stdin or the clipboard
+	// that can be opened without asking
+	ConfirmAbove int `yaml:"confirm_above"`
+
+	// PreviewCacheTTL is how long a cached page preview is used before it is
+	// revalidated with the site
+	PreviewCacheTTL time.Duration `yaml:"preview_cache_ttl"`
+}
+
+// DefaultHTTPTimeout is used when http_timeout is not set.
+const DefaultHTTPTimeout = 3 * time.Second
+
+// DefaultConfirmAbove is used when confirm_above is not set.
+const DefaultConfirmAbove = 10
+
+// DefaultPreviewCacheTTL is used when preview_cache_ttl is not set.
+const DefaultPreviewCacheTTL = 7 * 24 * time.Hour
+
+// Session is a named set of URLs, or shortcuts, opened together.
+type Session struct {
+	Profile string        `yaml:"profile,omitempty"`
+	URLs    []string      `yaml:"urls"`
+	Mode    string        `yaml:"mode,omitempty"`  // window (default), windows or tabs
+	Delay   time.Duration `yaml:"delay,omitempty"` // between tabs, e.g. 500ms
+}
+
+// Session modes.
+const (
+	SessionWindow  = "window"  // every URL in one new window
+	SessionWindows = "windows" // every URL in its own new window
+	SessionTabs    = "tabs"    // every URL in a new tab
+)
+
+// Handler configures "browsir handle", used when browsir is the default
+// browser of the desktop.
+type Handler struct {
+	Picker bool `yaml:"picker"` // ask for the profile when no route matches
+}
+
+// Menu configures "browsir menu", which lists the shortcuts and links in a
+// desktop launcher such as rofi.
+type Menu struct {
+	Launcher string `yaml:"launcher"` // used without --launcher
+
+	// Launchers map launcher names to command lines reading the entries on
+	// stdin and printing the chosen one, with a {prompt} placeholder
+	Launchers map[string]string `yaml:"launchers"`
+}
+
+// DefaultLauncher is used when menu.launcher is not set.
+const DefaultLauncher = "rofi"
+
+// DefaultLaunchers are always available, unless overridden in the config.
+var DefaultLaunchers = map[string]string{
+	"rofi":  "rofi -dmenu -i -p {prompt}",
+	"dmenu": "dmenu -i -l 20 -p {prompt}",
+	"wofi":  "wofi --dmenu --insensitive --prompt {prompt}",
+	"fzf":   "fzf --print-query --prompt={prompt}>",
+}
+
+// Route sends the URLs matching a pattern to a profile. The pattern is a glob
+// such as "*.atlassian.net", or a regular expression when prefixed with
+// "regex:". A route can also be written as "*.atlassian.net -> work".
+type Route struct {
+	Match   string `yaml:"match"`
+	Profile string `yaml:"profile"`
+}
+
+func (r *Route) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		match, profile, ok := strings.Cut(value.Value, "->")
+		if !ok {
+			return fmt.Errorf("line %d: route %q should look like '<pattern> -> <profile>'", value.Line, value.Value)
+		}
+		r.Match, r.Profile = strings.TrimSpace(match), strings.TrimSpace(profile)
+		return nil
+	}
+
+	type plain Route
+	return value.Decode((*plain)(r))
+}
+
+// Browser describes how to launch a browser.
+type Browser struct {
+	Path           string   `yaml:"path"`             // executable, wins over candidates
+	Candidates     []string `yaml:"candidates"`       // executables tried in order
+	Family         string   `yaml:"family"`           // chromium, gecko or custom
+	ProfileFlag    string   `yaml:"profile_flag"`     // e.g. "-profile {profile}"
+	IncognitoFlag  string   `yaml:"incognito_flag"`   // e.g. "--incognito"
+	NewWindowFlag  string   `yaml:"new_window_flag"`  // e.g. "--new-window"
+	AppFlag        string   `yaml:"app_flag"`         // e.g. "--app={url}"
+	KioskFlag      string   `yaml:"kiosk_flag"`       // e.g. "--kiosk"
+	WindowSizeFlag string   `yaml:"window_size_flag"` // e.g. "--window-size={width},{height}"
+}
+
+// LaunchMode changes how the browser opens a URL, on the command line or
+// per shortcut in shortcut_modes.
+type LaunchMode struct {
+	Private    bool   `yaml:"private,omitempty"`     // incognito or private window
+	NewWindow  bool   `yaml:"new_window,omitempty"`  // a new window instead of a tab
+	App        bool   `yaml:"app,omitempty"`         // a window without browser UI, for web apps
+	Kiosk      bool   `yaml:"kiosk,omitempty"`       // fullscreen, without browser UI
+	WindowSize string `yaml:"window_size,omitempty"` // WIDTHxHEIGHT, e.g. 1280x800
+}
+
+// DefaultSearchEngines are always available, unless overridden in the config.
+var DefaultSearchEngines = map[string]string{
+	"google":     "https://google.com/search?q={q}",
+	"duckduckgo": "https://duckduckgo.com/?q={q}",
+	"brave":      "https://search.brave.com/search?q={q}",
 }
 
 type Profile struct {
@@ -21,6 +158,23 @@ type Profile struct {
 	Description string `yaml:"description"`
 }
 
+// Storage selects where local shortcuts and links are kept.
+type Storage struct {
+	Backend   string `yaml:"backend"`   // "file" or "json"
+	Shortcuts string `yaml:"shortcuts"` // path of the shortcuts store
+	Links     string `yaml:"links"`     // path of the links store
+}
+
+// FindProfile returns the profile with the given name.
+func (c Config) FindProfile(name string) (Profile, bool) {
+	for _, p := range c.Profiles {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return Profile{}, false
+}
+
 func LoadConfig() (Config, error) {
 	configPath, err := findConfigFile()
 	if err != nil {
@@ -49,6 +203,40 @@ func LoadConfig() (Config, error) {
 			{Name: "default", ProfileDir: "Default", Description: "Default profile"},
 		}
 	}
+	if config.Storage.Backend == "" {
+		config.Storage.Backend = "file"
+	}
+	if config.SearchEngines == nil {
+		config.SearchEngines = make(map[string]string)
+	}
+	for name, template := range DefaultSearchEngines {
+		if _, ok := config.SearchEngines[name]; !ok {
+			config.SearchEngines[name] = template
+		}
+	}
+	if config.DefaultSearchEngine == "" {
+		config.DefaultSearchEngine = "google"
+	}
+	if config.Menu.Launcher == "" {
+		config.Menu.Launcher = DefaultLauncher
+	}
+	if config.Menu.Launchers == nil {
+		config.Menu.Launchers = make(map[string]string)
+	}
+	for name, command := range DefaultLaunchers {
+		if _, ok := config.Menu.Launchers[name]; !ok {
+			config.Menu.Launchers[name] = command
+		}
+	}
+	if config.HTTPTimeout == 0 {
+		config.HTTPTimeout = DefaultHTTPTimeout
+	}
+	if config.ConfirmAbove == 0 {
+		config.ConfirmAbove = DefaultConfirmAbove
+	}
+	if config.PreviewCacheTTL == 0 {
+		config.PreviewCacheTTL = DefaultPreviewCacheTTL
+	}
 	if config.Shortcuts == nil {
 		config.Shortcuts = map[string]string{
 			"cal": "calendar.google.com",
@@ -59,13 +247,7 @@ func LoadConfig() (Config, error) {
 }
 
 func findConfigFile() (string, error) {
-	configHome := os.Getenv("XDG_CONFIG_HOME")
-	if configHome == "" {
-		configHome = os.Getenv("HOME") + "/.config"
-	}
-	configPath := configHome + "/browsir/config.yml"
-
-	if _, err := os.Stat(configPath); err == nil {
+	if configPath, ok := ResolvePaths().ConfigFile(); ok {
 		return configPath, nil
 	}
 
@@ -72,6 +72,7 @@ func setupConfigDir(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Error creating config directory: %v", err)
 	}
+	t.Setenv("BROWSIR_HOME", configDir)
 	configFile := configDir + "/config.yml"
 	exampleFile, err := os.ReadFile("../config.example.yml")
 	if err != nil {
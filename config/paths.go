@@ -0,0 +1,110 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// SystemDir holds the machine-wide browsir files, e.g. team defaults.
+const SystemDir = "/etc/browsir"
+
+// Paths resolves the directories browsir reads its files from.
+//
+// Files are looked up in this order:
+//
+//  1. $BROWSIR_HOME, when set, replaces every per-user directory
+//  2. $XDG_DATA_HOME/browsir (default ~/.local/share/browsir), data files only
+//  3. $XDG_CONFIG_HOME/browsir (default ~/.config/browsir)
+//  4. /etc/browsir
+//
+// Caches go to $BROWSIR_HOME/cache, or $XDG_CACHE_HOME/browsir (default
+// ~/.cache/browsir).
+type Paths struct {
+	Home       string
+	DataHome   string
+	ConfigHome string
+	CacheHome  string
+	System     string
+}
+
+// ResolvePaths builds the Paths from the environment.
+func ResolvePaths() Paths {
+	home := os.Getenv("HOME")
+
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" {
+		dataHome = filepath.Join(home, ".local", "share")
+	}
+	configHome := os.Getenv("XDG_CONFIG_HOME")
+	if configHome == "" {
+		configHome = filepath.Join(home, ".config")
+	}
+	cacheHome := os.Getenv("XDG_CACHE_HOME")
+	if cacheHome == "" {
+		cacheHome = filepath.Join(home, ".cache")
+	}
+
+	return Paths{
+		Home:       os.Getenv("BROWSIR_HOME"),
+		DataHome:   filepath.Join(dataHome, "browsir"),
+		ConfigHome: filepath.Join(configHome, "browsir"),
+		CacheHome:  filepath.Join(cacheHome, "browsir"),
+		System:     SystemDir,
+	}
+}
+
+// ConfigFile returns the first existing config.yml, or false if none exists.
+func (p Paths) ConfigFile() (string, bool) {
+	return firstExisting("config.yml", append(p.userConfigDirs(), p.System)...)
+}
+
+// UserConfigFile returns where a per-user config.yml is, or would be, kept.
+func (p Paths) UserConfigFile() string {
+	return filepath.Join(p.userConfigDirs()[0], "config.yml")
+}
+
+// UserDataFile returns the per-user data file with the given name. An
+// existing file wins; otherwise the path it should be created at is returned.
+func (p Paths) UserDataFile(name string) string {
+	if path, ok := firstExisting(name, p.userDataDirs()...); ok {
+		return path
+	}
+	return filepath.Join(p.userDataDirs()[0], name)
+}
+
+// SystemDataFile returns the machine-wide data file with the given name.
+func (p Paths) SystemDataFile(name string) string {
+	return filepath.Join(p.System, name)
+}
+
+// CacheFile returns the per-user cache file with the given name.
+func (p Paths) CacheFile(name string) string {
+	if p.Home != "" {
+		return filepath.Join(p.Home, "cache", name)
+	}
+	return filepath.Join(p.CacheHome, name)
+}
+
+func (p Paths) userConfigDirs() []string {
+	if p.Home != "" {
+		return []string{p.Home}
+	}
+	return []string{p.ConfigHome}
+}
+
+func (p Paths) userDataDirs() []string {
+	if p.Home != "" {
+		return []string{p.Home}
+	}
+	return []string{p.DataHome, p.ConfigHome}
+}
+
+func firstExisting(name string, dirs ...string) (string, bool) {
+	for _, dir := range dirs {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err == nil {
+			return path, true
+		}
+	}
+	return "", false
+}
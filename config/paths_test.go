@@ -0,0 +1,72 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPaths(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_DATA_HOME", "")
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("BROWSIR_HOME", "")
+
+	dataDir := filepath.Join(home, ".local", "share", "browsir")
+	configDir := filepath.Join(home, ".config", "browsir")
+
+	t.Run("Test new data files go to the XDG data directory", func(t *testing.T) {
+		got := ResolvePaths().UserDataFile("links")
+		if want := filepath.Join(dataDir, "links"); got != want {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	})
+
+	t.Run("Test existing data files in the config directory are used", func(t *testing.T) {
+		writeFile(t, filepath.Join(configDir, "shortcuts"))
+		got := ResolvePaths().UserDataFile("shortcuts")
+		if want := filepath.Join(configDir, "shortcuts"); got != want {
+			t.Errorf("got %v, want %v", got, want)
+		}
+
+		writeFile(t, filepath.Join(dataDir, "shortcuts"))
+		got = ResolvePaths().UserDataFile("shortcuts")
+		if want := filepath.Join(dataDir, "shortcuts"); got != want {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	})
+
+	t.Run("Test caches go to the XDG cache directory", func(t *testing.T) {
+		got := ResolvePaths().CacheFile("previews.json")
+		if want := filepath.Join(home, ".cache", "browsir", "previews.json"); got != want {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	})
+
+	t.Run("Test BROWSIR_HOME overrides the per-user directories", func(t *testing.T) {
+		browsirHome := t.TempDir()
+		t.Setenv("BROWSIR_HOME", browsirHome)
+
+		if got, want := ResolvePaths().CacheFile("previews.json"), filepath.Join(browsirHome, "cache", "previews.json"); got != want {
+			t.Errorf("got %v, want %v", got, want)
+		}
+
+		got := ResolvePaths().UserDataFile("shortcuts")
+		if want := filepath.Join(browsirHome, "shortcuts"); got != want {
+			t.Errorf("got %v, want %v", got, want)
+		}
+		if _, ok := ResolvePaths().ConfigFile(); ok {
+			t.Errorf("got a config file, wanted none")
+		}
+	})
+}
+
+func writeFile(t *testing.T, path string) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("Error creating directory: %v", err)
+	}
+	if err := os.WriteFile(path, nil, 0644); err != nil {
+		t.Fatalf("Error writing file: %v", err)
+	}
+}
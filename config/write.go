@@ -0,0 +1,142 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/404answernotfound/browsir/storage"
+	"gopkg.in/yaml.v3"
+)
+
+// Path returns the config file in use, or where a new one would be created.
+func Path() string {
+	if configPath, err := findConfigFile(); err == nil {
+		return configPath
+	}
+	return ResolvePaths().UserConfigFile()
+}
+
+// AddProfiles appends profiles to the config file, skipping the ones whose
+// profile_dir is already configured, and returns how many were added. A
+// profile named like a configured one gets a numeric suffix, e.g. work-2.
+// Comments and the order of the existing entries are preserved.
+func AddProfiles(profiles []Profile) (int, error) {
+	added := 0
+	err := updateConfig(func(root *yaml.Node) error {
+		seq := mappingValue(root, "profiles", yaml.SequenceNode)
+
+		existing := make(map[string]bool)
+		names := make(map[string]bool)
+		for _, node := range seq.Content {
+			var p Profile
+			if err := node.Decode(&p); err == nil {
+				existing[p.ProfileDir] = true
+				names[p.Name] = true
+			}
+		}
+
+		for _, p := range profiles {
+			if existing[p.ProfileDir] {
+				continue
+			}
+			for name, i := p.Name, 2; names[p.Name]; i++ {
+				p.Name = fmt.Sprintf("%s-%d", name, i)
+			}
+			names[p.Name] = true
+			var node yaml.Node
+			if err := node.Encode(p); err != nil {
+				return err
+			}
+			seq.Content = append(seq.Content, &node)
+			existing[p.ProfileDir] = true
+			added++
+		}
+		return nil
+	})
+	return added, err
+}
+
+// SaveSession adds the session to the config file, replacing any session
+// with the same name.
+func SaveSession(name string, session Session) error {
+	return updateConfig(func(root *yaml.Node) error {
+		sessions := mappingValue(root, "sessions", yaml.MappingNode)
+
+		var node yaml.Node
+		if err := node.Encode(session); err != nil {
+			return err
+		}
+		for i := 0; i+1 < len(sessions.Content); i += 2 {
+			if sessions.Content[i].Value == name {
+				sessions.Content[i+1] = &node
+				return nil
+			}
+		}
+		sessions.Content = append(sessions.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: name}, &node)
+		return nil
+	})
+}
+
+// updateConfig loads the config file as a YAML node tree, lets edit change it
+// and writes it back.
+func updateConfig(edit func(root *yaml.Node) error) error {
+	configPath := Path()
+
+	data, err := os.ReadFile(configPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("error reading config file: %v", err)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("error parsing config file: %v", err)
+	}
+	if doc.Kind == 0 {
+		doc.Kind = yaml.DocumentNode
+		doc.Content = []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}
+	}
+
+	if err := edit(doc.Content[0]); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&doc); err != nil {
+		return err
+	}
+	if err := encoder.Close(); err != nil {
+		return err
+	}
+
+	// Keep the link of a config.yml symlinked by "make install", writing
+	// where it points
+	if target, err := filepath.EvalSymlinks(configPath); err == nil {
+		configPath = target
+	}
+	return storage.WriteFileAtomic(configPath, buf.Bytes(), 0644)
+}
+
+// mappingValue returns the value stored under key in a mapping node, adding
+// an empty node of the given kind when the key is missing.
+func mappingValue(mapping *yaml.Node, key string, kind yaml.Kind) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			value := mapping.Content[i+1]
+			if value.Kind != kind {
+				// e.g. "profiles:" left empty
+				value.Kind, value.Tag, value.Value = kind, "", ""
+			}
+			return value
+		}
+	}
+
+	keyNode := &yaml.Node{Kind: yaml.ScalarNode, Value: key}
+	value := &yaml.Node{Kind: kind}
+	mapping.Content = append(mapping.Content, keyNode, value)
+	return value
+}
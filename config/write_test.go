@@ -0,0 +1,103 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAddProfiles(t *testing.T) {
+	configDir := t.TempDir()
+	t.Setenv("BROWSIR_HOME", configDir)
+
+	configFile := filepath.Join(configDir, "config.yml")
+	err := os.WriteFile(configFile, []byte("browser_name: chrome # the browser\nprofiles:\n  - name: personal\n    profile_dir: Default\n"), 0644)
+	if err != nil {
+		t.Fatalf("Error writing config file: %v", err)
+	}
+
+	added, err := AddProfiles([]Profile{
+		{Name: "person-1", ProfileDir: "Default"},
+		{Name: "work", ProfileDir: "Profile 1", Description: "Work (chrome)"},
+		{Name: "personal", ProfileDir: "Profile 2"},
+		{Name: "personal", ProfileDir: "Profile 3"},
+	})
+	if err != nil {
+		t.Fatalf("Error adding profiles: %v", err)
+	}
+	if added != 3 {
+		t.Errorf("got %v, want %v", added, 3)
+	}
+
+	config, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("Error loading config: %v", err)
+	}
+	if len(config.Profiles) != 4 || config.Profiles[1].ProfileDir != "Profile 1" {
+		t.Errorf("got %+v", config.Profiles)
+	}
+	for i, want := range []string{"personal", "work", "personal-2", "personal-3"} {
+		if i < len(config.Profiles) && config.Profiles[i].Name != want {
+			t.Errorf("got profile %d named %v, want %v", i, config.Profiles[i].Name, want)
+		}
+	}
+
+	data, _ := os.ReadFile(configFile)
+	if !strings.Contains(string(data), "# the browser") {
+		t.Errorf("comments were not preserved:\n%s", data)
+	}
+}
+
+func TestSaveSession(t *testing.T) {
+	configDir := t.TempDir()
+	t.Setenv("BROWSIR_HOME", configDir)
+
+	configFile := filepath.Join(configDir, "config.yml")
+	err := os.WriteFile(configFile, []byte("sessions:\n  standup:\n    profile: work\n    urls: [jira-board]\n    delay: 500ms\n"), 0644)
+	if err != nil {
+		t.Fatalf("Error writing config file: %v", err)
+	}
+
+	err = SaveSession("research", Session{URLs: []string{"pkg.go.dev", "gh"}, Mode: SessionTabs})
+	if err != nil {
+		t.Fatalf("Error saving session: %v", err)
+	}
+
+	config, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("Error loading config: %v", err)
+	}
+	if got := config.Sessions["standup"].Delay; got != 500*time.Millisecond {
+		t.Errorf("got %v, want %v", got, 500*time.Millisecond)
+	}
+	if got := config.Sessions["research"]; len(got.URLs) != 2 || got.Mode != SessionTabs {
+		t.Errorf("got %+v", got)
+	}
+}
+
+func TestUpdateConfigSymlink(t *testing.T) {
+	configDir := t.TempDir()
+	t.Setenv("BROWSIR_HOME", configDir)
+
+	// As installed by "make install"
+	target := filepath.Join(t.TempDir(), "config.yml")
+	if err := os.WriteFile(target, []byte("browser_name: chrome\n"), 0644); err != nil {
+		t.Fatalf("Error writing config file: %v", err)
+	}
+	link := filepath.Join(configDir, "config.yml")
+	if err := os.Symlink(target, link); err != nil {
+		t.Fatalf("Error linking config file: %v", err)
+	}
+
+	if err := SaveSession("standup", Session{URLs: []string{"mail"}}); err != nil {
+		t.Fatalf("Error saving session: %v", err)
+	}
+	if info, err := os.Lstat(link); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("got %v, %v, want config.yml still a symlink", info, err)
+	}
+	if data, _ := os.ReadFile(target); !strings.Contains(string(data), "standup") {
+		t.Errorf("got %q, want the session written to the linked file", data)
+	}
+}
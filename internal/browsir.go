@@ -1,150 +1,380 @@
 package browsir
 
 import (
-	"context"
 	"fmt"
 	"os"
+	"sort"
 	"strings"
 
-	"io"
-	"net/http"
-	"time"
-
+	"github.com/404answernotfound/browsir/config"
+	"github.com/404answernotfound/browsir/storage"
 	"github.com/404answernotfound/browsir/utils"
-	"github.com/PuerkitoBio/goquery"
 )
 
-type ICommand interface {
-	add(args []string)
-	rm(args []string)
-	last(args []string)
-	preview(args []string)
+type Command struct {
+	config config.Config
 }
 
-type Command struct{}
+// addLink adds a link to the library, reading its title from the page when
+// none is given. The title and the note of a saved link can be changed too.
+func (c Command) addLink(ctx *Context) error {
+	link := ctx.Args[0]
 
-func (c Command) add(args []string) error {
-	switch args[0] {
-	case "link":
-		err := utils.CheckInputArgs(len(args), 2)
+	if _, exists := utils.GetLink(link); exists && (ctx.IsSet("title") || ctx.IsSet("note")) {
+		err := utils.UpdateLink(link, func(record *storage.Link) {
+			if ctx.IsSet("title") {
+				record.Title = ctx.String("title")
+			}
+			if ctx.IsSet("note") {
+				record.Note = ctx.String("note")
+			}
+		})
 		if err != nil {
-			os.Exit(0)
+			return err
 		}
+		fmt.Printf("Link %s correctly updated!\n", link)
+		return nil
+	}
 
-		flags := utils.GetFlags(args[2:])
-		link := args[1]
-
-		categories, ok := flags["-c"]
-		if !ok {
-			return fmt.Errorf("not a good flag")
+	record := storage.Link{
+		Categories: storage.SplitList(ctx.String("categories")),
+		Title:      ctx.String("title"),
+		Note:       ctx.String("note"),
+	}
+	if _, exists := utils.GetLink(link); !exists && record.Title == "" {
+		// Best effort, the link is saved without a title when offline
+		if preview, err := c.previewCache().Fetch(link, c.config.HTTPTimeout, false); err == nil {
+			record.Title = preview.Title
 		}
+	}
+	return utils.SaveLink(link, record)
+}
 
-		err = utils.SaveLink(link, categories)
-		if err != nil {
-			return err
+func (c Command) addShortcut(ctx *Context) error {
+	return utils.SaveLocalShortcut(ctx.Args[0], ctx.Args[1])
+}
+
+func (c Command) removeShortcut(ctx *Context) error {
+	return utils.RemoveLocalShortcut(ctx.Args[0])
+}
+
+// removeLinks removes links by exact URL, by URL prefix, by category or by
+// glob, asking for confirmation when more than one link would be removed.
+func (c Command) removeLinks(ctx *Context) error {
+	var link string
+	if len(ctx.Args) > 0 {
+		link = ctx.Args[0]
+	}
+	category := ctx.String("category")
+	pattern := ctx.String("match")
+
+	if link == "" && category == "" && pattern == "" {
+		return usageErrorf(ctx.Cmd, "provide a link, --category=<category> or --match=<glob>")
+	}
+
+	links, err := utils.LoadLinks()
+	if err != nil {
+		return err
+	}
+	matches := matchLinks(links, link, category, pattern)
+	if len(matches) == 0 {
+		return fmt.Errorf("no links matched")
+	}
+
+	// Only the link typed as stored goes without asking
+	if !(len(matches) == 1 && matches[0] == link) && !ctx.Bool("yes") {
+		fmt.Println("The following links will be removed:")
+		for _, l := range matches {
+			fmt.Printf("  %s\n", l)
 		}
-		return nil
-	case "shortcut":
-		err := utils.CheckInputArgs(len(args), 3)
-		if err != nil {
-			os.Exit(0)
+		prompt := fmt.Sprintf("Remove %d links?", len(matches))
+		if len(matches) == 1 {
+			prompt = "Remove this link?"
+		}
+		if !utils.PromptYesNo(prompt) {
+			return nil
 		}
+	}
 
-		shortcut := args[1]
-		url := args[2]
-		err = utils.SaveLocalShortcut(shortcut, url)
+	if err := utils.RemoveLinks(matches); err != nil {
 		return err
-	default:
-		return fmt.Errorf("unknown command: %s", args[0])
 	}
+
+	if len(matches) == 1 {
+		fmt.Printf("Link %s correctly removed!\n", matches[0])
+	} else {
+		fmt.Printf("%d links correctly removed!\n", len(matches))
+	}
+	return nil
 }
 
-func (c Command) remove(args []string) error {
-	switch args[0] {
-	case "link":
-		fmt.Println("rm link is not yet implemented")
-	case "shortcut":
-		err := utils.CheckInputArgs(len(args), 2)
-		if err != nil {
-			os.Exit(0)
+// migrate copies the files of an older install to the data directory.
+func (c Command) migrate(ctx *Context) error {
+	written, err := utils.MigrateLegacyFiles(ctx.Args[0], c.config.Storage)
+	for _, path := range written {
+		fmt.Printf("Copied to %s\n", path)
+	}
+	if err != nil {
+		return err
+	}
+	if len(written) == 0 {
+		fmt.Println("Nothing to migrate: no shortcuts or links there, or you already have them.")
+	}
+	return nil
+}
+
+// matchLinks returns, sorted, the links matching every one of link,
+// category and pattern that is not empty.
+func matchLinks(links map[string]storage.Link, link, category, pattern string) []string {
+	var matches []string
+	for l, record := range links {
+		if link != "" && !matchLink(link, l, links) {
+			continue
+		}
+		if category != "" && !utils.Contains(record.Categories, category) {
+			continue
+		}
+		if pattern != "" && !utils.MatchGlob(pattern, l) {
+			continue
 		}
+		matches = append(matches, l)
+	}
+	sort.Strings(matches)
+	return matches
+}
 
-		shortcut := args[1]
-		err = utils.RemoveLocalShortcut(shortcut)
-		if err != nil {
-			return err
+// matchLink reports whether candidate is the link given as argument, as
+// stored or, when the argument has no scheme, over https.
+func matchLink(link, candidate string, links map[string]storage.Link) bool {
+	if _, exact := links[link]; exact {
+		return candidate == link
+	}
+	return !strings.Contains(link, "://") && candidate == "https://"+link
+}
+
+func (c Command) list(ctx *Context) error {
+	links, err := utils.LoadLinks()
+	if err != nil {
+		return err
+	}
+	c.previewCache().FillTitles(links)
+	if ctx.IsSet("output") {
+		return printListing(ctx, utils.LinkListing(links))
+	}
+	for _, link := range storage.Keys(links) {
+		record := links[link]
+		fmt.Printf("Link: %s - Categories: %s\n", link, strings.Join(record.Categories, ","))
+		if record.Title != "" {
+			fmt.Printf("  Title: %s\n", record.Title)
+		}
+		if record.Note != "" {
+			fmt.Printf("  Note: %s\n", record.Note)
 		}
 	}
 	return nil
 }
 
-func (c Command) list(args []string) error {
-	links := utils.LoadLinks()
-	for link, categories := range links {
-		fmt.Printf("Link: %s - Categories: %s\n", link, categories)
+func (c Command) preview(ctx *Context) error {
+	preview, err := c.previewCache().Fetch(ctx.Args[0], c.config.HTTPTimeout, ctx.Bool("refresh"))
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case ctx.Bool("json"):
+		return utils.WriteListing(os.Stdout, utils.OutputJSON, preview)
+	case ctx.IsSet("output"):
+		return printListing(ctx, preview)
+	}
+	for _, row := range preview.Rows() {
+		fmt.Printf("%-22s %s\n", row[0]+":", row[1])
+	}
+	return nil
+}
+
+func (c Command) profiles(ctx *Context) error {
+	if ctx.IsSet("output") {
+		return printListing(ctx, utils.ProfileListing(c.config.Profiles))
 	}
+	utils.PrintProfiles(c.config.Profiles)
 	return nil
 }
 
-func (c Command) preview(args []string) error {
-	ctx := context.Background()
-	deadline := time.Now().Add(3000 * time.Millisecond)
-	ctx, cancel := context.WithDeadline(ctx, deadline)
-	defer cancel()
+func (c Command) discoverProfiles(ctx *Context) error {
+	browserName := ctx.String("browser")
+	if browserName == "" {
+		browserName = c.config.BrowserName
+	}
 
-	req, err := http.NewRequestWithContext(ctx, "GET", args[0], nil)
+	discovered, err := utils.DiscoverProfiles(browserName)
 	if err != nil {
-		return fmt.Errorf("error creating request: %s", err)
+		return err
+	}
+	switch {
+	case ctx.IsSet("output"):
+		if err := printListing(ctx, utils.DiscoveredListing(discovered)); err != nil {
+			return err
+		}
+	case len(discovered) == 0:
+		fmt.Printf("No profiles found for %s\n", browserName)
+		return nil
+	default:
+		fmt.Printf("Profiles found for %s:\n", browserName)
+		for _, p := range discovered {
+			fmt.Printf("  %-12s - %s\n", p.Dir, p.Name)
+		}
+	}
+
+	if !ctx.Bool("write") {
+		return nil
 	}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	profiles := make([]config.Profile, 0, len(discovered))
+	for _, p := range discovered {
+		profiles = append(profiles, p.Profile())
+	}
+	added, err := config.AddProfiles(profiles)
 	if err != nil {
-		return fmt.Errorf("error making request: %s", err)
+		return err
+	}
+	// Kept out of the listing
+	w := os.Stdout
+	if ctx.IsSet("output") {
+		w = os.Stderr
 	}
-	defer resp.Body.Close()
+	fmt.Fprintf(w, "%d profiles added to %s\n", added, config.Path())
+	return nil
+}
 
-	body, err := io.ReadAll(resp.Body)
+// open opens a URL, or a shortcut, in the profile picked by the routes.
+func (c Command) open(ctx *Context) error {
+	url, err := c.resolveTarget(ctx.Args[0], ctx.Args[1:])
 	if err != nil {
-		return fmt.Errorf("error reading response body: %s", err)
+		return err
 	}
 
-	doc, err := goquery.NewDocumentFromReader(strings.NewReader(string(body)))
+	match, err := utils.RouteURL(c.config.Routes, c.config.DefaultProfile, url)
+	if err != nil {
+		return err
+	}
+	profile, ok := c.config.FindProfile(match.Profile)
+	if !ok {
+		return fmt.Errorf("unknown profile %s picked for %s", match.Profile, url)
+	}
 
-	title := doc.Find("title").Text()
-	fmt.Printf("Title: %s\n", title)
+	opts, err := c.launchOptions(ctx)
+	if err != nil {
+		return err
+	}
+	opts = opts.WithMode(c.config.ShortcutModes[ctx.Args[0]])
+	return utils.OpenURLs(c.config.BrowserName, profile, []string{url}, opts)
+}
 
-	description, exists := doc.Find("meta[name='description']").Attr("content")
-	if exists {
-		fmt.Printf("Description: %s\n", description)
-	} else {
-		fmt.Println("No meta description found.")
+// launchFlags are the flags of the commands opening URLs.
+var launchFlags = []Flag{
+	{Name: "private", Usage: "Open in an incognito or private window", Bool: true},
+	{Name: "new-window", Usage: "Open in a new window", Bool: true},
+	{Name: "app", Usage: "Open as an app, in a window without browser UI (Chromium)", Bool: true},
+	{Name: "kiosk", Usage: "Open fullscreen, without browser UI", Bool: true},
+	{Name: "window-size", Usage: "Size of the window, e.g. 1280x800 (Chromium)"},
+}
+
+// launchOptions reads the launch flags.
+func (c Command) launchOptions(ctx *Context) (utils.LaunchOptions, error) {
+	opts := utils.LaunchOptions{
+		Private:    ctx.Bool("private"),
+		NewWindow:  ctx.Bool("new-window"),
+		App:        ctx.Bool("app"),
+		Kiosk:      ctx.Bool("kiosk"),
+		WindowSize: ctx.String("window-size"),
+	}
+	if opts.WindowSize != "" {
+		if _, _, err := utils.ParseWindowSize(opts.WindowSize); err != nil {
+			return opts, usageErrorf(ctx.Cmd, "%v", err)
+		}
 	}
+	return opts, nil
+}
 
-	allH1Tags := doc.Find("h1").Text()
-	fmt.Printf("H1 Tags: %v\n", allH1Tags)
+// launchGroup is one start of the browser, opening URLs with the same options.
+type launchGroup struct {
+	URLs    []string
+	Options utils.LaunchOptions
+}
 
-	return nil
+// launchGroups adds the shortcut_modes of the targets to opts, grouping the
+// targets that end up with the same options in one launch, so that the mode
+// of a shortcut only applies to its own URLs. Apps open one URL each, and
+// without targets the browser still starts once, on its start page.
+func (c Command) launchGroups(opts utils.LaunchOptions, targets []utils.Target) []launchGroup {
+	if len(targets) == 0 {
+		return []launchGroup{{Options: opts}}
+	}
+	var launches []launchGroup
+	index := make(map[utils.LaunchOptions]int)
+	for _, target := range targets {
+		o := opts.WithMode(c.config.ShortcutModes[target.Name])
+		if i, ok := index[o]; ok && !o.App {
+			launches[i].URLs = append(launches[i].URLs, target.URL)
+			continue
+		}
+		index[o] = len(launches)
+		launches = append(launches, launchGroup{URLs: []string{target.URL}, Options: o})
+	}
+	return launches
 }
 
-func RunCommand(mainCmd string, otherArgs []string) error {
-	command := &Command{}
-	var err error
+// openLaunches starts the browser once per launch, checking them all first
+// so that nothing opens when one of them can not.
+func (c Command) openLaunches(profile config.Profile, launches []launchGroup) error {
+	for _, l := range launches {
+		if err := utils.CheckLaunch(c.config.BrowserName, profile, l.URLs, l.Options); err != nil {
+			return err
+		}
+	}
+	for _, l := range launches {
+		if err := utils.OpenURLs(c.config.BrowserName, profile, l.URLs, l.Options); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-	switch mainCmd {
-	case "add":
-		err = command.add(otherArgs)
-		return err
-	case "rm":
-		err = command.remove(otherArgs)
-		return err
-	case "list":
-		err = command.list(otherArgs)
+// route explains which profile the routes pick for a URL.
+func (c Command) routeTest(ctx *Context) error {
+	url, err := c.resolveTarget(ctx.Args[0], ctx.Args[1:])
+	if err != nil {
 		return err
-	case "preview":
-		err = command.preview(otherArgs)
+	}
+
+	match, err := utils.RouteURL(c.config.Routes, c.config.DefaultProfile, url)
+	if err != nil {
 		return err
-	default:
-		return fmt.Errorf("not implemented")
 	}
+
+	fmt.Printf("Profile: %s\n", match.Profile)
+	if match.Route >= 0 {
+		route := c.config.Routes[match.Route]
+		fmt.Printf("Rule:    #%d %s -> %s\n", match.Route+1, route.Match, route.Profile)
+	} else {
+		fmt.Println("Rule:    default_profile")
+	}
+	fmt.Printf("Reason:  %s\n", match.Reason)
+	if _, ok := c.config.FindProfile(match.Profile); !ok {
+		fmt.Printf("Warning: profile %s is not configured\n", match.Profile)
+	}
+	return nil
+}
+
+// resolveTarget turns a URL or a shortcut, expanding templates with args,
+// into the URL to open.
+func (c Command) resolveTarget(target string, args []string) (string, error) {
+	url, ok := utils.ResolveShortcut(target, c.config.Shortcuts, utils.LoadLocalShortcuts())
+	if !ok {
+		return "", fmt.Errorf("unknown shortcut: %s", target)
+	}
+	if utils.IsTemplate(url) {
+		return utils.ExpandTemplate(url, args)
+	}
+	return url, nil
 }
@@ -0,0 +1,249 @@
+package browsir
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/404answernotfound/browsir/config"
+	"github.com/404answernotfound/browsir/storage"
+	"github.com/404answernotfound/browsir/utils"
+)
+
+// captureStdout returns what run printed on stdout.
+func captureStdout(t *testing.T, run func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Error creating pipe: %v", err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	out := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(r)
+		out <- string(data)
+	}()
+	run()
+	w.Close()
+	return <-out
+}
+
+func TestMatchLinks(t *testing.T) {
+	links := map[string]storage.Link{
+		"https://go.dev":               {Categories: []string{"go"}},
+		"https://go.dev/doc":           {Categories: []string{"go", "docs"}},
+		"https://github.com/golang/go": {Categories: []string{"go", "code"}},
+		"https://github.com/acme/api":  {Categories: []string{"work", "code"}},
+		"http://example.com":           {},
+	}
+
+	tcs := []struct {
+		name     string
+		link     string
+		category string
+		pattern  string
+		want     []string
+	}{
+		{"Test exact match", "https://go.dev", "", "", []string{"https://go.dev"}},
+		{"Test no prefix match", "https://github.com/", "", "", nil},
+		{"Test without scheme", "go.dev", "", "", []string{"https://go.dev"}},
+		{"Test https is the only implicit scheme", "example.com", "", "", nil},
+		{"Test category", "", "code", "", []string{"https://github.com/acme/api", "https://github.com/golang/go"}},
+		{"Test link within category", "github.com/acme/api", "work", "", []string{"https://github.com/acme/api"}},
+		{"Test link outside category", "go.dev", "code", "", nil},
+		{"Test glob", "", "", "*/golang/*", []string{"https://github.com/golang/go"}},
+		{"Test glob within category", "", "go", "https://go.dev*", []string{"https://go.dev", "https://go.dev/doc"}},
+		{"Test nothing matches", "", "missing", "", nil},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := matchLinks(links, tc.link, tc.category, tc.pattern); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestDiscoverProfilesOutput(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("the fixture is laid out like a Linux home")
+	}
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	localState := filepath.Join(home, ".config", "google-chrome", "Local State")
+	if err := os.MkdirAll(filepath.Dir(localState), 0755); err != nil {
+		t.Fatalf("Error creating chrome directory: %v", err)
+	}
+	if err := os.WriteFile(localState, []byte(`{"profile": {"info_cache": {"Profile 1": {"name": "Work"}}}}`), 0644); err != nil {
+		t.Fatalf("Error writing Local State: %v", err)
+	}
+
+	var code int
+	out := captureStdout(t, func() {
+		code = NewRootCmd(config.Config{}).Execute([]string{"profiles", "discover", "--browser=chrome", "-o", "json"})
+	})
+	if code != ExitOK {
+		t.Fatalf("got exit code %v, want %v", code, ExitOK)
+	}
+	var got []utils.ProfileEntry
+	if err := json.Unmarshal([]byte(out), &got); err != nil {
+		t.Fatalf("got %q, not JSON: %v", out, err)
+	}
+	want := []utils.ProfileEntry{{Name: "work", ProfileDir: "Profile 1", Description: "Work (chrome)", Source: utils.SourceBrowser}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestRemoveLinksConfirm(t *testing.T) {
+	links := storage.NewMemoryStore(map[string]storage.Link{
+		"https://go.dev":           {Categories: []string{"go"}},
+		"https://example.com/page": {},
+	})
+	utils.SetStores(storage.NewMemoryStore(map[string]string{}), links)
+	t.Cleanup(func() { utils.SetStores(nil, nil) })
+	// Without an answer, nothing asked for is removed
+	stdin := os.Stdin
+	os.Stdin, _ = os.Open(os.DevNull)
+	t.Cleanup(func() { os.Stdin.Close(); os.Stdin = stdin })
+
+	for _, args := range [][]string{{"example.com"}, {"go.dev"}, {"--category=go"}} {
+		captureStdout(t, func() {
+			NewRootCmd(config.Config{}).Execute(append([]string{"rm", "link"}, args...))
+		})
+	}
+	if got := len(links.List()); got != 2 {
+		t.Fatalf("got %v links, want both kept without confirmation", got)
+	}
+
+	captureStdout(t, func() {
+		NewRootCmd(config.Config{}).Execute([]string{"rm", "link", "https://go.dev"})
+	})
+	if _, ok := links.Get("https://go.dev"); ok {
+		t.Errorf("got https://go.dev kept, want it removed without asking")
+	}
+}
+
+func TestPreviewOutput(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(`<html><head><title>Example</title></head><body><h1>Hello</h1></body></html>`))
+	}))
+	defer server.Close()
+	cnf := config.Config{HTTPTimeout: time.Second}
+
+	for _, args := range [][]string{{"--json"}, {"-o", "json"}} {
+		var code int
+		out := captureStdout(t, func() {
+			code = NewRootCmd(cnf).Execute(append([]string{"preview", server.URL}, args...))
+		})
+		var got utils.Preview
+		if err := json.Unmarshal([]byte(out), &got); code != ExitOK || err != nil {
+			t.Fatalf("%v: got exit code %v and %q, not JSON: %v", args, code, out, err)
+		}
+		if got.Title != "Example" || !reflect.DeepEqual(got.H1, []string{"Hello"}) {
+			t.Errorf("%v: got %+v", args, got)
+		}
+	}
+
+	out := captureStdout(t, func() {
+		NewRootCmd(cnf).Execute([]string{"preview", server.URL, "-o", "tsv"})
+	})
+	if !strings.HasPrefix(out, "FIELD\tVALUE\n") || !strings.Contains(out, "Title\tExample\n") {
+		t.Errorf("got %q, want the fields as TSV", out)
+	}
+}
+
+func TestCacheStatsOutput(t *testing.T) {
+	cacheHome := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", cacheHome)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<html><head><title>Example</title></head></html>`))
+	}))
+	defer server.Close()
+	cnf := config.Config{HTTPTimeout: time.Second, PreviewCacheTTL: time.Hour}
+	captureStdout(t, func() { NewRootCmd(cnf).Execute([]string{"preview", server.URL}) })
+
+	var code int
+	out := captureStdout(t, func() {
+		code = NewRootCmd(cnf).Execute([]string{"cache", "stats", "-o", "json"})
+	})
+	var got utils.CacheStats
+	if err := json.Unmarshal([]byte(out), &got); code != ExitOK || err != nil {
+		t.Fatalf("got exit code %v and %q, not JSON: %v", code, out, err)
+	}
+	if got.Path != filepath.Join(cacheHome, "browsir", "previews.json") || got.Entries != 1 || got.Fresh != 1 || got.Stale != 0 || got.TTL != "1h0m0s" || got.Size == 0 {
+		t.Errorf("got %+v", got)
+	}
+}
+
+func TestLaunchGroups(t *testing.T) {
+	c := Command{config: config.Config{ShortcutModes: map[string]config.LaunchMode{
+		"mail":  {App: true},
+		"chat":  {App: true},
+		"news":  {NewWindow: true},
+		"paper": {NewWindow: true},
+	}}}
+	targets := []utils.Target{
+		{Name: "mail", URL: "mail.example.com"},
+		{Name: "go.dev", URL: "go.dev"},
+		{Name: "news", URL: "news.example.com"},
+		{Name: "chat", URL: "chat.example.com"},
+		{Name: "wiki", URL: "wiki.example.com"},
+		{Name: "paper", URL: "paper.example.com"},
+	}
+
+	private := utils.LaunchOptions{Private: true}
+	got := c.launchGroups(private, targets)
+	want := []launchGroup{
+		{URLs: []string{"mail.example.com"}, Options: utils.LaunchOptions{Private: true, App: true}},
+		{URLs: []string{"go.dev", "wiki.example.com"}, Options: private},
+		{URLs: []string{"news.example.com", "paper.example.com"}, Options: utils.LaunchOptions{Private: true, NewWindow: true}},
+		{URLs: []string{"chat.example.com"}, Options: utils.LaunchOptions{Private: true, App: true}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	if got, want := c.launchGroups(private, nil), []launchGroup{{Options: private}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v without targets, want %+v", got, want)
+	}
+}
+
+func TestOpenLaunches(t *testing.T) {
+	dir := t.TempDir()
+	browser := filepath.Join(dir, "browser")
+	if err := os.WriteFile(browser, []byte("#!/bin/sh\necho \"$@\" >> "+dir+"/launched\n"), 0755); err != nil {
+		t.Fatalf("Error writing the browser: %v", err)
+	}
+	utils.UseBrowsers(map[string]config.Browser{
+		"fake": {Path: browser, Family: utils.ChromiumFamily, NewWindowFlag: "--new-window", AppFlag: "--app={url}"},
+	})
+	utils.SetStores(storage.NewMemoryStore(map[string]string{}), storage.NewMemoryStore(map[string]storage.Link{}))
+	utils.SetHistory(storage.NewMemoryStore(map[string]time.Time{}))
+	t.Cleanup(func() { utils.UseBrowsers(nil); utils.SetStores(nil, nil); utils.SetHistory(nil) })
+
+	c := Command{config: config.Config{BrowserName: "fake"}}
+	err := c.openLaunches(config.Profile{}, []launchGroup{
+		{URLs: []string{"go.dev"}},
+		{URLs: []string{"mail.example.com"}, Options: utils.LaunchOptions{App: true, NewWindow: true}},
+	})
+	if err == nil {
+		t.Errorf("got no error for --app with --new-window")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "launched")); !os.IsNotExist(err) {
+		t.Errorf("got a launch before the failing one, want none")
+	}
+}
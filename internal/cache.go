@@ -0,0 +1,34 @@
+package browsir
+
+import (
+	"fmt"
+
+	"github.com/404answernotfound/browsir/utils"
+)
+
+// previewCache opens the preview cache with the TTL of the config.
+func (c Command) previewCache() *utils.PreviewCache {
+	return utils.OpenPreviewCache(c.config.PreviewCacheTTL)
+}
+
+func (c Command) cacheStats(ctx *Context) error {
+	stats := c.previewCache().Stats()
+	if ctx.IsSet("output") {
+		return printListing(ctx, stats)
+	}
+	fmt.Printf("  %-10s %s\n", "path", stats.Path)
+	fmt.Printf("  %-10s %d\n", "entries", stats.Entries)
+	fmt.Printf("  %-10s %d (younger than %s)\n", "fresh", stats.Fresh, stats.TTL)
+	fmt.Printf("  %-10s %d\n", "stale", stats.Stale)
+	fmt.Printf("  %-10s %.1f KiB\n", "size", float64(stats.Size)/1024)
+	return nil
+}
+
+func (c Command) clearCache(ctx *Context) error {
+	entries, err := c.previewCache().Clear()
+	if err != nil {
+		return fmt.Errorf("error clearing the preview cache: %v", err)
+	}
+	fmt.Printf("%d cached previews correctly removed!\n", entries)
+	return nil
+}
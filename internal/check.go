@@ -0,0 +1,135 @@
+package browsir
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/404answernotfound/browsir/storage"
+	"github.com/404answernotfound/browsir/utils"
+)
+
+// deadCategory tags the links found dead by "links check --tag-dead".
+const deadCategory = "dead"
+
+// checkLinks probes the links of the library, reporting the dead and moved
+// ones, and optionally fixing the library.
+func (c Command) checkLinks(ctx *Context) error {
+	timeout := c.config.HTTPTimeout
+	if ctx.IsSet("timeout") {
+		var err error
+		if timeout, err = time.ParseDuration(ctx.String("timeout")); err != nil {
+			return usageErrorf(ctx.Cmd, "invalid timeout: %s", ctx.String("timeout"))
+		}
+	}
+	workers := 8
+	if ctx.IsSet("workers") {
+		var err error
+		if workers, err = strconv.Atoi(ctx.String("workers")); err != nil || workers < 1 {
+			return usageErrorf(ctx.Cmd, "invalid number of workers: %s", ctx.String("workers"))
+		}
+	}
+
+	links, err := utils.LoadLinks()
+	if err != nil {
+		return err
+	}
+	if categories := storage.SplitList(ctx.String("category")); len(categories) > 0 {
+		matches := utils.SearchLinks(links, utils.LinkQuery{Categories: categories, AnyCategory: true})
+		links = make(map[string]storage.Link, len(matches))
+		for _, m := range matches {
+			links[m.URL] = m.Link
+		}
+	}
+	if len(links) == 0 {
+		fmt.Println("No links to check.")
+		return nil
+	}
+
+	results := utils.CheckLinks(storage.Keys(links), utils.CheckOptions{
+		Workers:  workers,
+		Timeout:  timeout,
+		OnResult: printLinkStatus,
+	})
+
+	var dead, failed, moved, fixed int
+	for _, r := range results {
+		switch {
+		case r.Dead():
+			dead++
+		case r.Failed():
+			failed++
+		case r.Moved:
+			moved++
+		}
+		changed, err := c.fixLink(ctx, r, links[r.URL])
+		if err != nil {
+			return err
+		}
+		if changed {
+			fixed++
+		}
+	}
+
+	fmt.Printf("\n%d links checked: %d dead, %d moved, %d errors", len(results), dead, moved, failed)
+	if fixed > 0 {
+		fmt.Printf(", %d updated", fixed)
+	}
+	fmt.Println()
+	return nil
+}
+
+// fixLink moves a link that moved permanently with --update, and tags or
+// untags it as dead with --tag-dead, reporting whether it changed. Failed
+// checks leave the tag as it is.
+func (c Command) fixLink(ctx *Context, r utils.LinkStatus, record storage.Link) (bool, error) {
+	if ctx.Bool("update") && r.Moved && !r.Dead() && !r.Failed() {
+		return true, utils.MoveLink(r.URL, r.FinalURL)
+	}
+
+	if !ctx.Bool("tag-dead") || r.Failed() {
+		return false, nil
+	}
+	tagged := utils.Contains(record.Categories, deadCategory)
+	if r.Dead() == tagged {
+		return false, nil
+	}
+	return true, utils.UpdateLink(r.URL, func(record *storage.Link) {
+		if r.Dead() {
+			record.Categories = append(record.Categories, deadCategory)
+			return
+		}
+		var categories []string
+		for _, c := range record.Categories {
+			if c != deadCategory {
+				categories = append(categories, c)
+			}
+		}
+		record.Categories = categories
+	})
+}
+
+func printLinkStatus(r utils.LinkStatus) {
+	state := "ok"
+	switch {
+	case r.Dead():
+		state = "dead"
+	case r.Failed():
+		state = "error"
+	case r.Moved:
+		state = "moved"
+	}
+
+	status := strconv.Itoa(r.Status)
+	if r.Err != nil {
+		status = "-"
+	}
+	fmt.Printf("  %-6s %-4s %6dms  %s", state, status, r.Latency.Milliseconds(), r.URL)
+	switch {
+	case r.Err != nil:
+		fmt.Printf(" (%v)", r.Err)
+	case r.FinalURL != "":
+		fmt.Printf(" -> %s", r.FinalURL)
+	}
+	fmt.Println()
+}
@@ -0,0 +1,442 @@
+package browsir
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Exit codes returned by Execute.
+const (
+	ExitOK    = 0
+	ExitError = 1 // the command failed
+	ExitUsage = 2 // the command was called the wrong way
+)
+
+// errQuiet fails a command that already explained why to the user.
+var errQuiet = errors.New("")
+
+// Cmd is a node of the command tree, e.g. "rm" or "rm link".
+type Cmd struct {
+	Name   string
+	Usage  string // arguments, e.g. "<link> [-c <categories>]"
+	Short  string // one line description
+	Hidden bool   // left out of help and completion listings
+
+	Flags        []Flag
+	DisableFlags bool                      // every argument is positional
+	Args         func(args []string) error // validates the positional arguments
+	Run          func(ctx *Context) error
+
+	// Completions suggests the next positional argument, given the previous
+	// ones.
+	Completions func(args []string) []string
+
+	Subcommands []*Cmd
+	parent      *Cmd
+}
+
+// Flag is a command line flag. Name is the long form (--name) and Short an
+// optional alias (-s); both can be given with one or two dashes.
+type Flag struct {
+	Name       string
+	Short      string
+	Usage      string
+	Bool       bool
+	Persistent bool            // also accepted by every subcommand
+	Values     func() []string // suggested values, for completion
+}
+
+// Context is what a command runs with.
+type Context struct {
+	Cmd     *Cmd
+	Args    []string
+	strings map[string]*string
+	bools   map[string]*bool
+	set     map[string]bool
+}
+
+// String returns the value of a string flag.
+func (ctx *Context) String(name string) string {
+	if v, ok := ctx.strings[name]; ok {
+		return *v
+	}
+	return ""
+}
+
+// Bool returns the value of a boolean flag.
+func (ctx *Context) Bool(name string) bool {
+	if v, ok := ctx.bools[name]; ok {
+		return *v
+	}
+	return false
+}
+
+// IsSet reports whether a flag was given on the command line.
+func (ctx *Context) IsSet(name string) bool {
+	return ctx.set[name]
+}
+
+// UsageError reports a command called the wrong way.
+type UsageError struct {
+	Cmd *Cmd
+	Err error
+}
+
+func (e *UsageError) Error() string {
+	return e.Err.Error()
+}
+
+func usageErrorf(cmd *Cmd, format string, args ...any) error {
+	return &UsageError{Cmd: cmd, Err: fmt.Errorf(format, args...)}
+}
+
+// NoArgs accepts no positional arguments.
+func NoArgs(args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("unexpected arguments: %s", strings.Join(args, " "))
+	}
+	return nil
+}
+
+// ExactArgs accepts exactly n positional arguments.
+func ExactArgs(n int) func([]string) error {
+	return RangeArgs(n, n)
+}
+
+// MinArgs accepts at least n positional arguments.
+func MinArgs(n int) func([]string) error {
+	return RangeArgs(n, -1)
+}
+
+// RangeArgs accepts between min and max positional arguments, max being
+// unbounded when negative.
+func RangeArgs(min, max int) func([]string) error {
+	return func(args []string) error {
+		if len(args) < min {
+			return fmt.Errorf("you provided %d arguments, while %d are needed", len(args), min)
+		}
+		if max >= 0 && len(args) > max {
+			return fmt.Errorf("unexpected arguments: %s", strings.Join(args[max:], " "))
+		}
+		return nil
+	}
+}
+
+// Path returns the full name of the command, e.g. "browsir rm link".
+func (cmd *Cmd) Path() string {
+	if cmd.parent == nil {
+		return cmd.Name
+	}
+	return cmd.parent.Path() + " " + cmd.Name
+}
+
+// Find returns the direct subcommand with the given name.
+func (cmd *Cmd) Find(name string) *Cmd {
+	for _, sub := range cmd.Subcommands {
+		if sub.Name == name {
+			return sub
+		}
+	}
+	return nil
+}
+
+// AddCommand attaches subcommands to cmd.
+func (cmd *Cmd) AddCommand(subs ...*Cmd) {
+	for _, sub := range subs {
+		sub.parent = cmd
+		cmd.Subcommands = append(cmd.Subcommands, sub)
+	}
+}
+
+// Resolve walks down the tree following args, returning the deepest command
+// they name and the arguments left for it.
+func (cmd *Cmd) Resolve(args []string) (*Cmd, []string) {
+	for len(args) > 0 {
+		sub := cmd.Find(args[0])
+		if sub == nil {
+			break
+		}
+		cmd, args = sub, args[1:]
+	}
+	return cmd, args
+}
+
+// Execute runs the command named by args and returns the exit code.
+func (cmd *Cmd) Execute(args []string) int {
+	target, rest := cmd.Resolve(args)
+
+	err := target.run(rest)
+	if err == nil {
+		return ExitOK
+	}
+	if errors.Is(err, flag.ErrHelp) {
+		target.PrintHelp(os.Stdout)
+		return ExitOK
+	}
+
+	if errors.Is(err, errQuiet) {
+		return ExitError
+	}
+
+	var usageErr *UsageError
+	if errors.As(err, &usageErr) {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", usageErr.Err)
+		fmt.Fprintf(os.Stderr, "Run '%s --help' for usage.\n", usageErr.Cmd.Path())
+		return ExitUsage
+	}
+
+	fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	return ExitError
+}
+
+func (cmd *Cmd) run(args []string) error {
+	ctx, err := cmd.parse(args)
+	if err != nil {
+		return err
+	}
+
+	if cmd.Run == nil {
+		if len(ctx.Args) > 0 {
+			return usageErrorf(cmd, "unknown command: %s %s", cmd.Path(), ctx.Args[0])
+		}
+		return usageErrorf(cmd, "%s needs a subcommand", cmd.Path())
+	}
+
+	if cmd.Args != nil {
+		if err := cmd.Args(ctx.Args); err != nil {
+			return &UsageError{Cmd: cmd, Err: err}
+		}
+	}
+	return cmd.Run(ctx)
+}
+
+// parse reads the flags of the command, which may be interleaved with the
+// positional arguments. Everything after "--" is positional.
+func (cmd *Cmd) parse(args []string) (*Context, error) {
+	ctx := &Context{
+		Cmd:     cmd,
+		strings: make(map[string]*string),
+		bools:   make(map[string]*bool),
+		set:     make(map[string]bool),
+	}
+
+	if cmd.DisableFlags {
+		ctx.Args = args
+		return ctx, nil
+	}
+
+	fs := flag.NewFlagSet(cmd.Path(), flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	names := make(map[string]string)
+	for _, f := range cmd.allFlags() {
+		for _, name := range []string{f.Name, f.Short} {
+			if name == "" {
+				continue
+			}
+			names[name] = f.Name
+			if f.Bool {
+				if ctx.bools[f.Name] == nil {
+					ctx.bools[f.Name] = new(bool)
+				}
+				fs.BoolVar(ctx.bools[f.Name], name, false, f.Usage)
+			} else {
+				if ctx.strings[f.Name] == nil {
+					ctx.strings[f.Name] = new(string)
+				}
+				fs.StringVar(ctx.strings[f.Name], name, "", f.Usage)
+			}
+		}
+	}
+
+	for {
+		if err := fs.Parse(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return nil, err
+			}
+			return nil, &UsageError{Cmd: cmd, Err: err}
+		}
+
+		rest := fs.Args()
+		consumed := len(args) - len(rest)
+		if consumed > 0 && args[consumed-1] == "--" {
+			ctx.Args = append(ctx.Args, rest...)
+			break
+		}
+		if len(rest) == 0 {
+			break
+		}
+		ctx.Args = append(ctx.Args, rest[0])
+		args = rest[1:]
+	}
+
+	fs.Visit(func(f *flag.Flag) {
+		ctx.set[names[f.Name]] = true
+	})
+	return ctx, nil
+}
+
+// PrintHelp prints the usage of the command, its flags and subcommands.
+func (cmd *Cmd) PrintHelp(w io.Writer) {
+	fmt.Fprintf(w, "Usage: %s", cmd.Path())
+	if cmd.Usage != "" {
+		fmt.Fprintf(w, " %s", cmd.Usage)
+	}
+	fmt.Fprintln(w)
+	if cmd.Short != "" {
+		fmt.Fprintf(w, "\n%s\n", cmd.Short)
+	}
+
+	printFlags(w, "Flags", cmd.Flags)
+	if cmd.parent != nil {
+		printFlags(w, "Global flags", cmd.parent.persistentFlags())
+	}
+
+	var visible []*Cmd
+	for _, sub := range cmd.Subcommands {
+		if !sub.Hidden {
+			visible = append(visible, sub)
+		}
+	}
+	if len(visible) > 0 {
+		fmt.Fprintln(w, "\nCommands:")
+		for _, sub := range visible {
+			fmt.Fprintf(w, "  %-12s %s\n", sub.Name, sub.Short)
+		}
+	}
+}
+
+// Complete returns the suggestions for the last of args, the word being
+// typed, following the command tree like Execute does.
+func (cmd *Cmd) Complete(args []string) []string {
+	if len(args) == 0 {
+		args = []string{""}
+	}
+	words, current := args[:len(args)-1], args[len(args)-1]
+
+	var positional []string
+	var pending *Flag // string flag waiting for its value
+	leading, dashDash := true, false
+	for _, word := range words {
+		switch {
+		case pending != nil:
+			pending = nil
+		case dashDash || word == "-" || !strings.HasPrefix(word, "-"):
+			if sub := cmd.Find(word); leading && !dashDash && sub != nil {
+				cmd = sub
+				continue
+			}
+			positional = append(positional, word)
+			leading = false
+		case word == "--":
+			dashDash, leading = true, false
+		default:
+			leading = false
+			if f := cmd.flag(word); f != nil && !f.Bool && !strings.Contains(word, "=") {
+				pending = f
+			}
+		}
+	}
+
+	var candidates []string
+	switch {
+	case pending != nil:
+		candidates = flagValues(pending, "")
+	case !dashDash && strings.HasPrefix(current, "-") && strings.Contains(current, "="):
+		name, _, _ := strings.Cut(current, "=")
+		if f := cmd.flag(name); f != nil {
+			candidates = flagValues(f, name+"=")
+		}
+	case !dashDash && strings.HasPrefix(current, "-"):
+		for _, f := range cmd.allFlags() {
+			candidates = append(candidates, "--"+f.Name)
+			if f.Short != "" {
+				candidates = append(candidates, "-"+f.Short)
+			}
+		}
+	default:
+		if leading {
+			for _, sub := range cmd.Subcommands {
+				if !sub.Hidden {
+					candidates = append(candidates, sub.Name)
+				}
+			}
+		}
+		if cmd.Completions != nil {
+			candidates = append(candidates, cmd.Completions(positional)...)
+		}
+	}
+
+	var matches []string
+	seen := make(map[string]bool)
+	for _, candidate := range candidates {
+		if strings.HasPrefix(candidate, current) && !seen[candidate] {
+			seen[candidate] = true
+			matches = append(matches, candidate)
+		}
+	}
+	return matches
+}
+
+// flag returns the flag named by a command line word such as "--name=x".
+func (cmd *Cmd) flag(word string) *Flag {
+	name, _, _ := strings.Cut(strings.TrimLeft(word, "-"), "=")
+	for _, f := range cmd.allFlags() {
+		if f.Name == name || (f.Short != "" && f.Short == name) {
+			return &f
+		}
+	}
+	return nil
+}
+
+// allFlags returns the flags of cmd and the persistent flags of its parents.
+func (cmd *Cmd) allFlags() []Flag {
+	flags := cmd.Flags
+	if cmd.parent != nil {
+		flags = append(flags[:len(flags):len(flags)], cmd.parent.persistentFlags()...)
+	}
+	return flags
+}
+
+func (cmd *Cmd) persistentFlags() []Flag {
+	var flags []Flag
+	for c := cmd; c != nil; c = c.parent {
+		for _, f := range c.Flags {
+			if f.Persistent {
+				flags = append(flags, f)
+			}
+		}
+	}
+	return flags
+}
+
+func printFlags(w io.Writer, title string, flags []Flag) {
+	if len(flags) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "\n%s:\n", title)
+	for _, f := range flags {
+		name := "    --" + f.Name
+		if f.Short != "" {
+			name = "-" + f.Short + ", --" + f.Name
+		}
+		if !f.Bool {
+			name += "=<value>"
+		}
+		fmt.Fprintf(w, "  %-28s %s\n", name, f.Usage)
+	}
+}
+
+func flagValues(f *Flag, prefix string) []string {
+	if f.Values == nil {
+		return nil
+	}
+	var values []string
+	for _, v := range f.Values() {
+		values = append(values, prefix+v)
+	}
+	return values
+}
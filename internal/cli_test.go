@@ -0,0 +1,90 @@
+package browsir
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCmd(t *testing.T) {
+	var got *Context
+	run := func(ctx *Context) error {
+		got = ctx
+		return nil
+	}
+
+	root := &Cmd{Name: "browsir", Run: run}
+	rm := &Cmd{Name: "rm"}
+	rm.AddCommand(&Cmd{
+		Name: "link",
+		Flags: []Flag{
+			{Name: "category", Short: "c"},
+			{Name: "yes", Short: "y", Bool: true},
+		},
+		Args: RangeArgs(0, 1),
+		Run:  run,
+	})
+	root.AddCommand(rm)
+
+	t.Run("Test flags interleaved with arguments", func(t *testing.T) {
+		code := root.Execute([]string{"rm", "link", "-y", "https://go.dev", "--category=go"})
+		if code != ExitOK {
+			t.Fatalf("got exit code %v, want %v", code, ExitOK)
+		}
+		if got.Cmd.Path() != "browsir rm link" || len(got.Args) != 1 || got.Args[0] != "https://go.dev" {
+			t.Errorf("got %v %v", got.Cmd.Path(), got.Args)
+		}
+		if got.String("category") != "go" || !got.Bool("yes") || !got.IsSet("category") {
+			t.Errorf("got category %v and yes %v", got.String("category"), got.Bool("yes"))
+		}
+	})
+
+	t.Run("Test arguments after -- are positional", func(t *testing.T) {
+		root.Execute([]string{"rm", "link", "--", "-weird"})
+		if len(got.Args) != 1 || got.Args[0] != "-weird" {
+			t.Errorf("got %v", got.Args)
+		}
+	})
+
+	t.Run("Test names that only contain a command go to the root", func(t *testing.T) {
+		root.Execute([]string{"playlist", "mail"})
+		if got.Cmd != root || got.Args[0] != "playlist" {
+			t.Errorf("got %v %v", got.Cmd.Path(), got.Args)
+		}
+	})
+
+	t.Run("Test usage errors", func(t *testing.T) {
+		for _, args := range [][]string{
+			{"rm"},
+			{"rm", "nope"},
+			{"rm", "link", "a", "b"},
+			{"rm", "link", "--unknown"},
+		} {
+			if code := root.Execute(args); code != ExitUsage {
+				t.Errorf("%v: got exit code %v, want %v", args, code, ExitUsage)
+			}
+		}
+	})
+
+	t.Run("Test completion", func(t *testing.T) {
+		rm.Subcommands[0].Flags[0].Values = func() []string { return []string{"go", "news"} }
+		rm.Subcommands[0].Completions = func(args []string) []string { return []string{"https://go.dev"} }
+
+		tests := []struct {
+			args []string
+			want []string
+		}{
+			{[]string{""}, []string{"rm"}},
+			{[]string{"rm", ""}, []string{"link"}},
+			{[]string{"rm", "link", "--c"}, []string{"--category"}},
+			{[]string{"rm", "link", "--category=n"}, []string{"--category=news"}},
+			{[]string{"rm", "link", "-c", ""}, []string{"go", "news"}},
+			{[]string{"rm", "link", "-y", "https://"}, []string{"https://go.dev"}},
+		}
+		for _, tt := range tests {
+			got := root.Complete(tt.args)
+			if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+				t.Errorf("%v: got %v, want %v", tt.args, got, tt.want)
+			}
+		}
+	})
+}
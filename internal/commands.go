@@ -0,0 +1,357 @@
+package browsir
+
+import (
+	"os"
+	"strings"
+
+	"github.com/404answernotfound/browsir/config"
+	"github.com/404answernotfound/browsir/utils"
+)
+
+// Execute runs the browsir command line and returns its exit code.
+func Execute(cnf config.Config, args []string) int {
+	return NewRootCmd(cnf).Execute(args)
+}
+
+// NewRootCmd builds the browsir command tree. Arguments that do not name a
+// subcommand go to the root command: "browsir <profile> [url|shortcut]...".
+func NewRootCmd(cnf config.Config) *Cmd {
+	c := Command{config: cnf}
+
+	root := &Cmd{
+		Name:  "browsir",
+		Usage: "[profile] [url|shortcut]...",
+		Short: "Open websites and shortcuts in the right browser profile",
+		Flags: append([]Flag{
+			{Name: "help", Short: "h", Usage: "Print the help message", Bool: true},
+			{Name: "version", Short: "v", Usage: "Print browsir version", Bool: true},
+			{Name: "list-shortcuts", Short: "ls", Usage: "List all shortcuts", Bool: true},
+			{Name: "profiles", Short: "p", Usage: "List all profiles", Bool: true},
+			{Name: "query", Short: "q", Usage: "Search the web with a query"},
+			{Name: "search-engine", Short: "se", Usage: "Search engine used by --query", Values: c.engineNames},
+			{Name: "output", Short: "o", Usage: "Print listings as json, yaml, tsv or table", Persistent: true, Values: staticValues(utils.OutputFormats...)},
+			{Name: "clipboard", Usage: "Also open the URLs in the clipboard, as \"-\" does with stdin", Bool: true},
+			{Name: "yes", Short: "y", Usage: "Open the URLs of stdin or the clipboard without asking", Bool: true},
+		}, launchFlags...),
+		Run: c.root,
+		Completions: func(args []string) []string {
+			switch len(args) {
+			case 0:
+				return c.profileNames()
+			case 1:
+				return c.shortcutNames()
+			}
+			return nil
+		},
+	}
+
+	add := &Cmd{Name: "add", Short: "Add a link or a local shortcut"}
+	add.AddCommand(
+		&Cmd{
+			Name:  "link",
+			Usage: "<link> [-c <categories>] [--title=<title>] [--note=<note>]",
+			Short: "Add a link with comma separated categories, or change its title and note",
+			Flags: []Flag{
+				{Name: "categories", Short: "c", Usage: "Comma separated categories", Values: c.categoryNames},
+				{Name: "title", Short: "t", Usage: "Title, read from the page by default"},
+				{Name: "note", Short: "n", Usage: "Free-form note"},
+			},
+			Args: ExactArgs(1),
+			Run:  c.addLink,
+		},
+		&Cmd{
+			Name:  "shortcut",
+			Usage: "<shortcut> <url>",
+			Short: "Add a local shortcut, do not include http:// or https://",
+			Args:  ExactArgs(2),
+			Run:   c.addShortcut,
+		},
+	)
+
+	rm := &Cmd{Name: "rm", Short: "Remove links or a local shortcut"}
+	rm.AddCommand(
+		&Cmd{
+			Name:  "link",
+			Usage: "[<link>] [--category=<category>] [--match=<glob>]",
+			Short: "Remove a link, every link in a category or every link matching a glob",
+			Flags: []Flag{
+				{Name: "category", Short: "c", Usage: "Remove every link in a category", Values: c.categoryNames},
+				{Name: "match", Usage: "Remove every link matching a glob"},
+				{Name: "yes", Short: "y", Usage: "Do not ask for confirmation", Bool: true},
+			},
+			Args:        RangeArgs(0, 1),
+			Run:         c.removeLinks,
+			Completions: firstArg(c.linkNames),
+		},
+		&Cmd{
+			Name:        "shortcut",
+			Usage:       "<shortcut>",
+			Short:       "Remove a local shortcut",
+			Args:        ExactArgs(1),
+			Run:         c.removeShortcut,
+			Completions: firstArg(c.localShortcutNames),
+		},
+	)
+
+	profiles := &Cmd{
+		Name:  "profiles",
+		Short: "List or discover browser profiles",
+		Args:  NoArgs,
+		Run:   c.profiles,
+	}
+	profiles.AddCommand(&Cmd{
+		Name:  "discover",
+		Usage: "[--browser=<browser>] [--write]",
+		Short: "Find the profiles of a browser, optionally adding them to config.yml",
+		Flags: []Flag{
+			{Name: "browser", Usage: "Browser to inspect, browser_name by default", Values: c.browserNames},
+			{Name: "write", Usage: "Add the missing profiles to config.yml", Bool: true},
+		},
+		Args: NoArgs,
+		Run:  c.discoverProfiles,
+	})
+
+	importCmd := &Cmd{
+		Name:  "import",
+		Usage: "<file> [--format=html|chromium|firefox] [--category=<categories>] [--dry-run] [--yes]",
+		Short: "Import browser bookmarks into the link library, folders becoming categories",
+		Flags: []Flag{
+			{Name: "format", Usage: "html, chromium or firefox, detected by default", Values: staticValues(utils.NetscapeBookmarks, utils.ChromiumBookmarks, utils.FirefoxBookmarks)},
+			{Name: "category", Short: "c", Usage: "Comma separated categories added to every link", Values: c.categoryNames},
+			{Name: "dry-run", Short: "n", Usage: "Only show what would be imported", Bool: true},
+			{Name: "yes", Short: "y", Usage: "Do not ask for confirmation", Bool: true},
+		},
+		Args: ExactArgs(1),
+		Run:  c.importBookmarks,
+	}
+
+	exportCmd := &Cmd{
+		Name:  "export",
+		Usage: "[--format=html|md|csv|opml|json] [--category=<categories>] [--out=<file>]",
+		Short: "Export the link library, e.g. to load it in a browser or a wiki",
+		Flags: []Flag{
+			{Name: "format", Short: "f", Usage: "html, md, csv, opml or json, from the --out extension by default", Values: staticValues(utils.ExportFormats...)},
+			{Name: "category", Short: "c", Usage: "Comma separated categories to export, all by default", Values: c.categoryNames},
+			{Name: "out", Usage: "File to write, stdout by default"},
+		},
+		Args: NoArgs,
+		Run:  c.export,
+	}
+
+	links := &Cmd{Name: "links", Short: "Search and check the link library"}
+	links.AddCommand(&Cmd{
+		Name:  "search",
+		Usage: "[<term>...] [--category=<a,b>] [--any] [--fuzzy] [--open=<profile>]",
+		Short: "Find links by URL and category",
+		Flags: []Flag{
+			{Name: "category", Short: "c", Usage: "Comma separated categories the links must all have", Values: c.categoryNames},
+			{Name: "any", Usage: "Links need only one of the categories", Bool: true},
+			{Name: "fuzzy", Short: "f", Usage: "Match the terms loosely and rank the results", Bool: true},
+			{Name: "open", Usage: "Open the top hit, or the one picked, in a profile", Values: c.profileNames},
+		},
+		Run: c.searchLinks,
+	}, &Cmd{
+		Name:  "check",
+		Usage: "[--category=<categories>] [--timeout=<duration>] [--workers=<n>] [--update] [--tag-dead]",
+		Short: "Find dead and moved links",
+		Flags: []Flag{
+			{Name: "category", Short: "c", Usage: "Comma separated categories to check, all by default", Values: c.categoryNames},
+			{Name: "timeout", Usage: "Timeout of every link, http_timeout by default"},
+			{Name: "workers", Usage: "Links checked at the same time, 8 by default"},
+			{Name: "update", Usage: "Replace the links that moved permanently with their new URL", Bool: true},
+			{Name: "tag-dead", Usage: "Add the dead category to dead links, removing it from the others", Bool: true},
+		},
+		Args: NoArgs,
+		Run:  c.checkLinks,
+	})
+
+	cache := &Cmd{Name: "cache", Short: "Inspect or clear the preview cache"}
+	cache.AddCommand(&Cmd{
+		Name:  "stats",
+		Short: "Show where the preview cache is, its size and entries",
+		Args:  NoArgs,
+		Run:   c.cacheStats,
+	}, &Cmd{
+		Name:  "clear",
+		Short: "Remove every cached preview",
+		Args:  NoArgs,
+		Run:   c.clearCache,
+	})
+
+	route := &Cmd{Name: "route", Short: "Inspect the URL routing rules"}
+	route.AddCommand(&Cmd{
+		Name:        "test",
+		Usage:       "<url>",
+		Short:       "Show which route matches a URL and why",
+		Args:        MinArgs(1),
+		Run:         c.routeTest,
+		Completions: firstArg(c.shortcutNames),
+	})
+
+	session := &Cmd{
+		Name:        "session",
+		Usage:       "[<name>]",
+		Short:       "Open every URL of a session, or list the sessions",
+		Args:        RangeArgs(0, 1),
+		Run:         c.session,
+		Completions: firstArg(c.sessionNames),
+	}
+	session.AddCommand(&Cmd{
+		Name:        "open",
+		Usage:       "<name>",
+		Short:       "Open every URL of a session, even one named like a subcommand",
+		Args:        ExactArgs(1),
+		Run:         c.session,
+		Completions: firstArg(c.sessionNames),
+	}, &Cmd{
+		Name:  "save",
+		Usage: "<name> [--profile=<profile>] [--mode=window|windows|tabs] [--delay=<duration>] <url|shortcut>...",
+		Short: "Save URLs and shortcuts as a session",
+		Flags: []Flag{
+			{Name: "profile", Usage: "Profile the session opens in", Values: c.profileNames},
+			{Name: "mode", Usage: "window, windows or tabs", Values: staticValues(config.SessionWindow, config.SessionWindows, config.SessionTabs)},
+			{Name: "delay", Usage: "Delay between tabs, e.g. 500ms"},
+		},
+		Args: MinArgs(2),
+		Run:  c.saveSession,
+		Completions: func(args []string) []string {
+			if len(args) == 0 {
+				return c.sessionNames()
+			}
+			return c.shortcutNames()
+		},
+	})
+
+	root.AddCommand(
+		add,
+		rm,
+		&Cmd{
+			Name:  "list",
+			Usage: "[links|all]",
+			Short: "List all links and categories",
+			Args:  RangeArgs(0, 1),
+			Run:   c.list,
+		},
+		links,
+		&Cmd{
+			Name:  "pick",
+			Usage: "[query] [--profile=<profile>]",
+			Short: "Find a shortcut, link or recent URL with a fuzzy finder, and open or copy it",
+			Flags: []Flag{
+				{Name: "profile", Short: "p", Usage: "Profile to open in, default_profile by default", Values: c.profileNames},
+			},
+			Run: c.pick,
+		},
+		&Cmd{
+			Name:  "menu",
+			Usage: "[--launcher=rofi|dmenu|wofi|fzf] [--profile=<profile>]",
+			Short: "Pick a shortcut, link or recent URL, then a profile, in a desktop launcher",
+			Flags: []Flag{
+				{Name: "launcher", Short: "l", Usage: "Launcher from menu.launchers, menu.launcher by default", Values: c.launcherNames},
+				{Name: "profile", Short: "p", Usage: "Profile to open in, asked by default", Values: c.profileNames},
+			},
+			Args: NoArgs,
+			Run:  c.menu,
+		},
+		importCmd,
+		exportCmd,
+		&Cmd{
+			Name:  "migrate",
+			Usage: "<dir>",
+			Short: "Copy the shortcuts and links files that older versions read from <dir>",
+			Args:  ExactArgs(1),
+			Run:   c.migrate,
+		},
+		&Cmd{
+			Name:  "preview",
+			Usage: "<link> [--json] [--refresh]",
+			Short: "Preview a link, from the cache while it is fresh",
+			Flags: []Flag{
+				{Name: "json", Usage: "Print the preview as JSON, the same as --output json", Bool: true},
+				{Name: "refresh", Usage: "Download the page again, ignoring the cache", Bool: true},
+			},
+			Args:        ExactArgs(1),
+			Run:         c.preview,
+			Completions: firstArg(c.linkNames),
+		},
+		cache,
+		&Cmd{
+			Name:        "open",
+			Usage:       "<url|shortcut> [template args...] [--private] [--new-window] [--app] [--kiosk] [--window-size=WxH]",
+			Short:       "Open with the profile picked by the routes",
+			Flags:       launchFlags,
+			Args:        MinArgs(1),
+			Run:         c.open,
+			Completions: firstArg(c.shortcutNames),
+		},
+		&Cmd{
+			Name:  "search",
+			Usage: "[--profile=<profile>] [--engine=<engine>] <query>...",
+			Short: "Search the web, a leading !bang picks the engine",
+			Flags: []Flag{
+				{Name: "profile", Short: "p", Usage: "Profile to search with, default_profile by default", Values: c.profileNames},
+				{Name: "engine", Short: "se", Usage: "Search engine, default_search_engine by default", Values: c.engineNames},
+			},
+			Args: MinArgs(1),
+			Run:  c.search,
+		},
+		profiles,
+		route,
+		&Cmd{
+			Name:  "handle",
+			Usage: "<url>...",
+			Short: "Open links clicked in other applications, when browsir is the default browser",
+			Flags: []Flag{
+				{Name: "desktop-file", Usage: "Print the .desktop file registering browsir as a browser", Bool: true},
+			},
+			Run: c.handle,
+		},
+		session,
+		&Cmd{
+			Name:        "completion",
+			Usage:       "bash|zsh|fish",
+			Short:       "Print the shell completion script",
+			Args:        ExactArgs(1),
+			Run:         c.completion,
+			Completions: firstArg(staticValues("bash", "fish", "zsh")),
+		},
+		&Cmd{
+			Name:         "__complete",
+			Short:        "Print the completions of a command line",
+			Hidden:       true,
+			DisableFlags: true,
+			Run:          complete(root),
+		},
+		&Cmd{
+			Name:  "help",
+			Usage: "[command]...",
+			Short: "Show the help of a command",
+			Run: func(ctx *Context) error {
+				if len(ctx.Args) == 0 {
+					return c.usage(ctx, utils.LoadLocalShortcuts())
+				}
+				cmd, rest := root.Resolve(ctx.Args)
+				if len(rest) > 0 {
+					return usageErrorf(ctx.Cmd, "unknown command: %s", strings.Join(ctx.Args, " "))
+				}
+				cmd.PrintHelp(os.Stdout)
+				return nil
+			},
+			Completions: func(args []string) []string {
+				cmd, rest := root.Resolve(args)
+				if len(rest) > 0 {
+					return nil
+				}
+				var names []string
+				for _, sub := range cmd.Subcommands {
+					if !sub.Hidden {
+						names = append(names, sub.Name)
+					}
+				}
+				return names
+			},
+		},
+	)
+
+	return root
+}
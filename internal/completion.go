@@ -0,0 +1,148 @@
+package browsir
+
+import (
+	"fmt"
+
+	"github.com/404answernotfound/browsir/storage"
+	"github.com/404answernotfound/browsir/utils"
+)
+
+// The completion scripts ask "browsir __complete" for the candidates of the
+// word being typed, passing every word typed so far.
+const bashCompletion = `# bash completion for browsir
+# Load it with: source <(browsir completion bash)
+_browsir() {
+    local line="${COMP_LINE:0:COMP_POINT}"
+    local -a words
+    read -ra words <<< "$line"
+    [[ "$line" == *" " ]] && words+=("")
+
+    local IFS=$'\n'
+    local -a candidates
+    candidates=($(browsir __complete "${words[@]:1}" 2>/dev/null))
+
+    # bash splits --flag=value on "=", only the value is replaced
+    local current="${words[${#words[@]}-1]}"
+    if [[ "$current" == *=* && "$COMP_WORDBREAKS" == *=* ]]; then
+        candidates=("${candidates[@]#*=}")
+    fi
+    COMPREPLY=("${candidates[@]}")
+}
+complete -o default -F _browsir browsir
+`
+
+const zshCompletion = `#compdef browsir
+# zsh completion for browsir
+# Load it with: source <(browsir completion zsh)
+_browsir() {
+    local -a candidates
+    candidates=(${(f)"$(browsir __complete "${(@)words[2,CURRENT]}" 2>/dev/null)"})
+    compadd -Q -- "${candidates[@]}"
+}
+compdef _browsir browsir
+`
+
+const fishCompletion = `# fish completion for browsir
+# Load it with: browsir completion fish | source
+function __browsir_complete
+    set -l tokens (commandline -opc) (commandline -ct)
+    browsir __complete $tokens[2..-1] 2>/dev/null
+end
+complete -c browsir -f -a '(__browsir_complete)'
+`
+
+var completionScripts = map[string]string{
+	"bash": bashCompletion,
+	"zsh":  zshCompletion,
+	"fish": fishCompletion,
+}
+
+// completion prints the completion script of a shell.
+func (c Command) completion(ctx *Context) error {
+	script, ok := completionScripts[ctx.Args[0]]
+	if !ok {
+		return usageErrorf(ctx.Cmd, "unsupported shell: %s, use bash, zsh or fish", ctx.Args[0])
+	}
+	fmt.Print(script)
+	return nil
+}
+
+// complete prints the candidates for the last argument, one per line.
+func complete(root *Cmd) func(ctx *Context) error {
+	return func(ctx *Context) error {
+		for _, candidate := range root.Complete(ctx.Args) {
+			fmt.Println(candidate)
+		}
+		return nil
+	}
+}
+
+func (c Command) profileNames() []string {
+	var names []string
+	for _, p := range c.config.Profiles {
+		names = append(names, p.Name)
+	}
+	return names
+}
+
+func (c Command) shortcutNames() []string {
+	names := storage.Keys(c.config.Shortcuts)
+	return append(names, c.localShortcutNames()...)
+}
+
+func (c Command) localShortcutNames() []string {
+	return storage.Keys(utils.LoadLocalShortcuts())
+}
+
+func (c Command) linkNames() []string {
+	links, _ := utils.LoadLinks()
+	return storage.Keys(links)
+}
+
+func (c Command) categoryNames() []string {
+	seen := make(map[string]string)
+	links, _ := utils.LoadLinks()
+	for _, record := range links {
+		for _, category := range record.Categories {
+			seen[category] = category
+		}
+	}
+	return storage.Keys(seen)
+}
+
+func (c Command) engineNames() []string {
+	return storage.Keys(c.config.SearchEngines)
+}
+
+func (c Command) launcherNames() []string {
+	return storage.Keys(c.config.Menu.Launchers)
+}
+
+func (c Command) sessionNames() []string {
+	return storage.Keys(c.config.Sessions)
+}
+
+func (c Command) browserNames() []string {
+	seen := make(map[string]string)
+	for name := range utils.DefaultBrowsers() {
+		seen[name] = name
+	}
+	for name := range c.config.Browsers {
+		seen[name] = name
+	}
+	return storage.Keys(seen)
+}
+
+// firstArg completes the first positional argument only.
+func firstArg(values func() []string) func(args []string) []string {
+	return func(args []string) []string {
+		if len(args) == 0 {
+			return values()
+		}
+		return nil
+	}
+}
+
+func staticValues(values ...string) func() []string {
+	return func() []string { return values }
+}
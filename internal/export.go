@@ -0,0 +1,67 @@
+package browsir
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/404answernotfound/browsir/storage"
+	"github.com/404answernotfound/browsir/utils"
+)
+
+// export writes the link library, or some of its categories, to stdout or
+// to a file.
+func (c Command) export(ctx *Context) error {
+	out := ctx.String("out")
+	format := ctx.String("format")
+	if format == "" {
+		format = exportFormat(out)
+	}
+	if !utils.Contains(utils.ExportFormats, format) {
+		return usageErrorf(ctx.Cmd, "unknown export format: %s, use %s", format, strings.Join(utils.ExportFormats, ", "))
+	}
+
+	links, err := utils.LoadLinks()
+	if err != nil {
+		return err
+	}
+	categories := storage.SplitList(ctx.String("category"))
+	if len(categories) > 0 {
+		matches := utils.SearchLinks(links, utils.LinkQuery{Categories: categories, AnyCategory: true})
+		links = make(map[string]storage.Link, len(matches))
+		for _, m := range matches {
+			links[m.URL] = m.Link
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := utils.ExportLinks(&buf, format, links, categories); err != nil {
+		return err
+	}
+
+	if out == "" || out == "-" {
+		_, err := os.Stdout.Write(buf.Bytes())
+		return err
+	}
+	if err := storage.WriteFileAtomic(out, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("error writing %s: %v", out, err)
+	}
+	fmt.Printf("%d links exported to %s\n", len(links), out)
+	return nil
+}
+
+// exportFormat picks the format from the extension of the output file,
+// Netscape HTML otherwise.
+func exportFormat(out string) string {
+	switch ext := strings.TrimPrefix(filepath.Ext(out), "."); ext {
+	case "htm":
+		return utils.ExportHTML
+	case "markdown":
+		return utils.ExportMarkdown
+	case utils.ExportMarkdown, utils.ExportCSV, utils.ExportOPML, utils.ExportJSON:
+		return ext
+	}
+	return utils.ExportHTML
+}
@@ -0,0 +1,133 @@
+package browsir
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/404answernotfound/browsir/config"
+	"github.com/404answernotfound/browsir/utils"
+)
+
+// desktopEntry registers browsir as a handler of http and https links, see
+// https://specifications.freedesktop.org/desktop-entry-spec/latest/
+const desktopEntry = `[Desktop Entry]
+Type=Application
+Version=1.0
+Name=Browsir
+GenericName=Web Browser
+Comment=Open links in the right browser profile
+Exec=%s handle %%u
+Terminal=false
+NoDisplay=true
+MimeType=x-scheme-handler/http;x-scheme-handler/https;
+Categories=Network;WebBrowser;
+`
+
+// handle opens the links given by the desktop, e.g. when browsir is the
+// default browser and a link is clicked in another application.
+func (c Command) handle(ctx *Context) error {
+	if ctx.Bool("desktop-file") {
+		return printDesktopEntry()
+	}
+
+	urls := ctx.Args
+	if len(urls) == 0 {
+		return usageErrorf(ctx.Cmd, "provide the url to open")
+	}
+
+	for _, url := range urls {
+		profile, err := c.handlerProfile(url)
+		if err != nil {
+			return err
+		}
+		if err := utils.OpenBrowser(c.config.BrowserName, profile, url); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// handlerProfile picks the profile for a link: the matching route, else the
+// picker when enabled, else default_profile, else the first profile, so a
+// clicked link always opens somewhere.
+func (c Command) handlerProfile(url string) (config.Profile, error) {
+	match, err := utils.RouteURL(c.config.Routes, "", url)
+	if err != nil && !errors.Is(err, utils.ErrNoRoute) {
+		return config.Profile{}, err
+	}
+	if err == nil {
+		if profile, ok := c.config.FindProfile(match.Profile); ok {
+			return profile, nil
+		}
+		return config.Profile{}, fmt.Errorf("unknown profile %s picked for %s", match.Profile, url)
+	}
+
+	if c.config.Handler.Picker {
+		profile, err := pickProfile(c.config.Profiles, url)
+		if err == nil {
+			return profile, nil
+		}
+		fmt.Fprintf(os.Stderr, "Picker: %v\n", err)
+	}
+
+	if profile, ok := c.config.FindProfile(c.config.DefaultProfile); ok {
+		return profile, nil
+	}
+	return c.config.Profiles[0], nil
+}
+
+// pickProfile asks which profile should open url, in the terminal when there
+// is one and with zenity otherwise.
+func pickProfile(profiles []config.Profile, url string) (config.Profile, error) {
+	if utils.IsTerminal(os.Stdin) {
+		options := make([]string, 0, len(profiles))
+		for _, p := range profiles {
+			options = append(options, fmt.Sprintf("%-12s - %s", p.Name, p.Description))
+		}
+		fmt.Printf("Open %s with:\n", url)
+		choice, err := utils.PromptChoice("Profile", options)
+		if err != nil {
+			return config.Profile{}, err
+		}
+		return profiles[choice], nil
+	}
+
+	zenity, err := exec.LookPath("zenity")
+	if err != nil {
+		return config.Profile{}, errors.New("no terminal and zenity is not installed")
+	}
+
+	zenityArgs := []string{"--list", "--title=browsir", "--text=Open " + url + " with", "--column=Profile", "--column=Description"}
+	for _, p := range profiles {
+		zenityArgs = append(zenityArgs, p.Name, p.Description)
+	}
+	out, err := exec.Command(zenity, zenityArgs...).Output()
+	if err != nil {
+		return config.Profile{}, fmt.Errorf("no profile picked: %v", err)
+	}
+
+	name := strings.TrimSpace(string(out))
+	for _, p := range profiles {
+		if p.Name == name {
+			return p, nil
+		}
+	}
+	return config.Profile{}, fmt.Errorf("unknown profile %s", name)
+}
+
+// printDesktopEntry prints the .desktop file registering the running browsir
+// binary as a handler of web links.
+func printDesktopEntry() error {
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	if strings.ContainsAny(executable, " \t\"") {
+		executable = `"` + strings.ReplaceAll(executable, `"`, `\"`) + `"`
+	}
+	fmt.Printf(desktopEntry, executable)
+	return nil
+}
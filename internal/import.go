@@ -0,0 +1,143 @@
+package browsir
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/404answernotfound/browsir/storage"
+	"github.com/404answernotfound/browsir/utils"
+)
+
+// importBookmarks adds the bookmarks of a browser to the link library,
+// their folders becoming categories. It shows what would be added first.
+func (c Command) importBookmarks(ctx *Context) error {
+	bookmarks, err := utils.ReadBookmarks(ctx.Args[0], ctx.String("format"))
+	if err != nil {
+		return err
+	}
+
+	extra := storage.SplitList(ctx.String("category"))
+	links, err := utils.LoadLinks()
+	if err != nil {
+		return err
+	}
+	plan := planImport(bookmarks, links, extra)
+
+	fmt.Printf("Found %d bookmarks in %s:\n", len(bookmarks), ctx.Args[0])
+	fmt.Printf("  %-10s %d\n", "new", len(plan.urls))
+	fmt.Printf("  %-10s %d\n", "existing", plan.existing)
+	fmt.Printf("  %-10s %d\n", "duplicate", plan.duplicates)
+	fmt.Printf("  %-10s %d (not http or https)\n", "skipped", plan.skipped)
+	if len(plan.urls) > 0 {
+		fmt.Println("\nNew links by category:")
+		for _, category := range storage.Keys(plan.categories) {
+			fmt.Printf("  %-24s %d\n", category, plan.categories[category])
+		}
+	}
+
+	if len(plan.urls) == 0 || ctx.Bool("dry-run") {
+		return nil
+	}
+	if !ctx.Bool("yes") && !utils.PromptYesNo(fmt.Sprintf("Import %d links?", len(plan.urls))) {
+		return nil
+	}
+
+	for _, url := range plan.urls {
+		if err := utils.SaveLink(url, plan.records[url]); err != nil {
+			return fmt.Errorf("error saving %s: %v", url, err)
+		}
+	}
+	fmt.Printf("%d links correctly imported!\n", len(plan.urls))
+	return nil
+}
+
+type importPlan struct {
+	urls       []string // new links, in the order of the bookmarks
+	records    map[string]storage.Link
+	categories map[string]int // new links per category
+	existing   int            // already in the library
+	duplicates int            // bookmarked more than once, merged
+	skipped    int
+}
+
+// planImport turns bookmarks into new link records, skipping the links
+// already saved and merging the categories of duplicate bookmarks.
+func planImport(bookmarks []utils.Bookmark, links map[string]storage.Link, extra []string) importPlan {
+	plan := importPlan{records: make(map[string]storage.Link), categories: make(map[string]int)}
+
+	saved := make(map[string]bool, len(links))
+	for url := range links {
+		saved[linkKey(url)] = true
+	}
+
+	byKey := make(map[string]string)
+	for _, b := range bookmarks {
+		if !strings.HasPrefix(b.URL, "http://") && !strings.HasPrefix(b.URL, "https://") {
+			plan.skipped++
+			continue
+		}
+		key := linkKey(b.URL)
+		if saved[key] {
+			plan.existing++
+			continue
+		}
+
+		categories := append(folderCategories(b.Folders), extra...)
+		if url, ok := byKey[key]; ok {
+			plan.duplicates++
+			record := plan.records[url]
+			for _, c := range categories {
+				if !utils.Contains(record.Categories, c) {
+					record.Categories = append(record.Categories, c)
+				}
+			}
+			plan.records[url] = record
+			continue
+		}
+
+		byKey[key] = b.URL
+		plan.urls = append(plan.urls, b.URL)
+		plan.records[b.URL] = storage.Link{Categories: dedupe(categories), Title: b.Title, Created: b.Added}
+	}
+
+	for _, url := range plan.urls {
+		categories := plan.records[url].Categories
+		if len(categories) == 0 {
+			categories = []string{"general"}
+		}
+		for _, c := range categories {
+			plan.categories[c]++
+		}
+	}
+	return plan
+}
+
+// linkKey compares links regardless of a trailing slash.
+func linkKey(url string) string {
+	return strings.TrimSuffix(url, "/")
+}
+
+// folderCategories turns folder names into categories, which can not hold
+// the separators of the links file.
+func folderCategories(folders []string) []string {
+	replacer := strings.NewReplacer(",", " ", "|", " ")
+	var categories []string
+	for _, folder := range folders {
+		if folder = strings.TrimSpace(replacer.Replace(folder)); folder != "" {
+			categories = append(categories, folder)
+		}
+	}
+	return categories
+}
+
+func dedupe(items []string) []string {
+	var result []string
+	seen := make(map[string]bool)
+	for _, item := range items {
+		if !seen[item] {
+			seen[item] = true
+			result = append(result, item)
+		}
+	}
+	return result
+}
@@ -0,0 +1,35 @@
+package browsir
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/404answernotfound/browsir/storage"
+	"github.com/404answernotfound/browsir/utils"
+)
+
+func TestPlanImport(t *testing.T) {
+	bookmarks := []utils.Bookmark{
+		{URL: "https://go.dev/", Title: "Go", Folders: []string{"Dev"}},
+		{URL: "https://go.dev", Title: "Go again", Folders: []string{"Go, the language"}},
+		{URL: "https://github.com/", Title: "GitHub", Folders: []string{"Dev"}},
+		{URL: "https://news.ycombinator.com/"},
+		{URL: "place:sort=8"},
+	}
+	links := map[string]storage.Link{"https://github.com": {Categories: []string{"code"}}}
+
+	plan := planImport(bookmarks, links, []string{"imported"})
+
+	if !reflect.DeepEqual(plan.urls, []string{"https://go.dev/", "https://news.ycombinator.com/"}) {
+		t.Errorf("got urls %v", plan.urls)
+	}
+	if got := plan.records["https://go.dev/"].Categories; !reflect.DeepEqual(got, []string{"Dev", "imported", "Go  the language"}) {
+		t.Errorf("got categories %q", got)
+	}
+	if plan.existing != 1 || plan.duplicates != 1 || plan.skipped != 1 {
+		t.Errorf("got %d existing, %d duplicates and %d skipped, want 1 each", plan.existing, plan.duplicates, plan.skipped)
+	}
+	if plan.categories["imported"] != 2 || plan.categories["Dev"] != 1 {
+		t.Errorf("got category counts %v", plan.categories)
+	}
+}
@@ -0,0 +1,77 @@
+package browsir
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/404answernotfound/browsir/utils"
+)
+
+// inputURLs takes "-" out of the targets, reading URLs from stdin instead,
+// and reads the URLs of the clipboard too with fromClipboard. Only http and
+// https URLs are kept, once each.
+func inputURLs(targets []string, stdin io.Reader, fromClipboard bool) ([]string, []string, error) {
+	var rest []string
+	var text strings.Builder
+	fromStdin := false
+	for _, target := range targets {
+		if target != "-" {
+			rest = append(rest, target)
+			continue
+		}
+		if fromStdin {
+			continue
+		}
+		fromStdin = true
+		data, err := io.ReadAll(stdin)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error reading stdin: %v", err)
+		}
+		text.Write(data)
+		text.WriteString("\n")
+	}
+	if fromClipboard {
+		clip, err := utils.ReadClipboard()
+		if err != nil {
+			return nil, nil, err
+		}
+		text.WriteString(clip)
+	}
+	if !fromStdin && !fromClipboard {
+		return rest, nil, nil
+	}
+
+	urls := utils.ExtractURLs(text.String())
+	if len(urls) == 0 {
+		source := "stdin"
+		if fromClipboard {
+			source = "the clipboard"
+			if fromStdin {
+				source = "stdin and the clipboard"
+			}
+		}
+		return nil, nil, fmt.Errorf("no http or https URLs found in %s", source)
+	}
+	return rest, urls, nil
+}
+
+// confirmURLs asks before opening more URLs than confirm_above, reading the
+// answer from the terminal when stdin held the URLs.
+func (c Command) confirmURLs(urls []string, stdinUsed bool) (bool, error) {
+	if len(urls) <= c.config.ConfirmAbove {
+		return true, nil
+	}
+
+	answers := io.Reader(os.Stdin)
+	if stdinUsed {
+		tty, err := os.Open("/dev/tty")
+		if err != nil {
+			return false, fmt.Errorf("%d URLs to open, more than confirm_above (%d), use --yes to open them", len(urls), c.config.ConfirmAbove)
+		}
+		defer tty.Close()
+		answers = tty
+	}
+	return utils.PromptYesNoFrom(answers, fmt.Sprintf("Open %d URLs?", len(urls))), nil
+}
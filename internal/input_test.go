@@ -0,0 +1,42 @@
+package browsir
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/404answernotfound/browsir/utils"
+)
+
+func TestInputURLs(t *testing.T) {
+	utils.UseClipboard(&utils.FakeClipboard{Text: "copied https://go.dev and https://example.com/a"})
+	t.Cleanup(func() { utils.UseClipboard(nil) })
+	stdin := "https://example.com/a\nhttps://example.com/b\n"
+
+	tcs := []struct {
+		name      string
+		targets   []string
+		stdin     string
+		clipboard bool
+		wantRest  []string
+		wantURLs  []string
+		wantErr   bool
+	}{
+		{"Test no input", []string{"mail", "cal"}, stdin, false, []string{"mail", "cal"}, nil, false},
+		{"Test stdin", []string{"mail", "-"}, stdin, false, []string{"mail"}, []string{"https://example.com/a", "https://example.com/b"}, false},
+		{"Test clipboard", []string{"mail"}, stdin, true, []string{"mail"}, []string{"https://go.dev", "https://example.com/a"}, false},
+		{"Test stdin and clipboard are deduplicated", []string{"-"}, stdin, true, nil, []string{"https://example.com/a", "https://example.com/b", "https://go.dev"}, false},
+		{"Test no URLs", []string{"-"}, "nothing here\n", false, nil, nil, true},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			rest, urls, err := inputURLs(tc.targets, strings.NewReader(tc.stdin), tc.clipboard)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tc.wantErr)
+			}
+			if !reflect.DeepEqual(rest, tc.wantRest) || !reflect.DeepEqual(urls, tc.wantURLs) {
+				t.Errorf("got %q and %q, want %q and %q", rest, urls, tc.wantRest, tc.wantURLs)
+			}
+		})
+	}
+}
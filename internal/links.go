@@ -0,0 +1,76 @@
+package browsir
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/404answernotfound/browsir/storage"
+	"github.com/404answernotfound/browsir/utils"
+)
+
+// searchLinks looks for links by URL and category, printing them or opening
+// one with --open.
+func (c Command) searchLinks(ctx *Context) error {
+	query := utils.LinkQuery{
+		Terms:       ctx.Args,
+		Categories:  storage.SplitList(ctx.String("category")),
+		AnyCategory: ctx.Bool("any"),
+		Fuzzy:       ctx.Bool("fuzzy"),
+	}
+	if len(query.Terms) == 0 && len(query.Categories) == 0 {
+		return usageErrorf(ctx.Cmd, "provide search terms or --category=<categories>")
+	}
+
+	links, err := utils.LoadLinks()
+	if err != nil {
+		return err
+	}
+	c.previewCache().FillTitles(links)
+	matches := utils.SearchLinks(links, query)
+
+	if ctx.IsSet("open") {
+		return c.openMatch(ctx.String("open"), matches)
+	}
+	if ctx.IsSet("output") {
+		return printListing(ctx, utils.MatchListing(matches))
+	}
+
+	if len(matches) == 0 {
+		fmt.Println("No links found.")
+		return nil
+	}
+	for _, m := range matches {
+		fmt.Printf("  %s - %s\n", m.URL, strings.Join(m.Categories, ","))
+		if m.Title != "" {
+			fmt.Printf("    %s\n", m.Title)
+		}
+	}
+	return nil
+}
+
+// openMatch opens the only match, or the one picked in the terminal when
+// there are more, falling back to the top hit.
+func (c Command) openMatch(profileName string, matches []utils.LinkMatch) error {
+	profile, ok := c.config.FindProfile(profileName)
+	if !ok {
+		return fmt.Errorf("unknown profile: %s", profileName)
+	}
+	if len(matches) == 0 {
+		return fmt.Errorf("no links matched")
+	}
+
+	choice := 0
+	if len(matches) > 1 && utils.IsTerminal(os.Stdin) {
+		options := make([]string, 0, len(matches))
+		for _, m := range matches {
+			options = append(options, m.URL)
+		}
+		var err error
+		if choice, err = utils.PromptChoice("Which link do you want to open?", options); err != nil {
+			return err
+		}
+	}
+
+	return utils.OpenBrowser(c.config.BrowserName, profile, matches[choice].URL)
+}
@@ -0,0 +1,130 @@
+package browsir
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/404answernotfound/browsir/config"
+	"github.com/404answernotfound/browsir/utils"
+)
+
+// menuChoice is what was picked in the launcher: the URLs to open, or the
+// query to search, and the profile.
+type menuChoice struct {
+	Launches []launchGroup
+	Query    string
+	Profile  config.Profile
+}
+
+// menu lists the shortcuts, links and recent URLs in a desktop launcher such
+// as rofi, then the profiles, and opens what was picked. Free text is opened
+// when it names URLs or shortcuts, and searched otherwise.
+func (c Command) menu(ctx *Context) error {
+	choice, err := c.menuSelect(ctx.String("launcher"), ctx.String("profile"))
+	if errors.Is(err, utils.ErrNothingPicked) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if choice.Query != "" {
+		return c.searchWith(choice.Profile, "", choice.Query)
+	}
+	return c.openLaunches(choice.Profile, choice.Launches)
+}
+
+// menuSelect asks the launcher what to open, then in which profile unless
+// profileName is given or there is only one.
+func (c Command) menuSelect(launcher, profileName string) (menuChoice, error) {
+	if launcher == "" {
+		launcher = c.config.Menu.Launcher
+	}
+	command, ok := c.config.Menu.Launchers[launcher]
+	if !ok {
+		return menuChoice{}, fmt.Errorf("unknown launcher: %s", launcher)
+	}
+	if _, ok := c.config.FindProfile(profileName); profileName != "" && !ok {
+		return menuChoice{}, fmt.Errorf("unknown profile: %s", profileName)
+	}
+
+	links, err := utils.LoadLinks()
+	if err != nil {
+		return menuChoice{}, err
+	}
+	c.previewCache().FillTitles(links)
+	localShortcuts := utils.LoadLocalShortcuts()
+	items := utils.PickItems(c.config.Shortcuts, localShortcuts, links, utils.RecentURLs(recentInPicker))
+	entries := make([]string, 0, len(items))
+	byLine := make(map[string]utils.PickItem, len(items))
+	for _, item := range items {
+		line := pickLine(item)
+		entries = append(entries, line)
+		byLine[line] = item
+	}
+
+	picked, err := utils.RunLauncher(command, "browsir", entries)
+	if err != nil {
+		return menuChoice{}, err
+	}
+
+	var choice menuChoice
+	if item, ok := byLine[picked]; ok {
+		url, err := pickedURL(item)
+		if err != nil {
+			return menuChoice{}, err
+		}
+		target := utils.Target{URL: url}
+		if item.Kind == utils.PickShortcut {
+			target.Name = item.Name
+		}
+		choice.Launches = c.launchGroups(utils.LaunchOptions{}, []utils.Target{target})
+	} else {
+		targets, unknown, err := utils.ResolveTargets(strings.Fields(picked), c.config.Shortcuts, localShortcuts)
+		if err != nil {
+			return menuChoice{}, err
+		}
+		if len(unknown) > 0 {
+			choice.Query = picked
+		} else {
+			choice.Launches = c.launchGroups(utils.LaunchOptions{}, targets)
+		}
+	}
+
+	choice.Profile, err = c.menuProfile(command, profileName)
+	return choice, err
+}
+
+// menuProfile asks the launcher for the profile, default_profile listed
+// first so that it is the one preselected.
+func (c Command) menuProfile(command, profileName string) (config.Profile, error) {
+	if profileName != "" {
+		profile, _ := c.config.FindProfile(profileName)
+		return profile, nil
+	}
+	if len(c.config.Profiles) == 1 {
+		return c.config.Profiles[0], nil
+	}
+
+	var entries []string
+	for _, p := range c.config.Profiles {
+		entry := fmt.Sprintf("%-12s - %s", p.Name, p.Description)
+		if p.Name == c.config.DefaultProfile {
+			entries = append([]string{entry}, entries...)
+		} else {
+			entries = append(entries, entry)
+		}
+	}
+
+	picked, err := utils.RunLauncher(command, "profile", entries)
+	if err != nil {
+		return config.Profile{}, err
+	}
+	name, _, _ := strings.Cut(picked, " ")
+	profile, ok := c.config.FindProfile(name)
+	if !ok {
+		return config.Profile{}, fmt.Errorf("unknown profile: %s", name)
+	}
+	return profile, nil
+}
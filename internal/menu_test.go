@@ -0,0 +1,105 @@
+package browsir
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/404answernotfound/browsir/config"
+	"github.com/404answernotfound/browsir/storage"
+	"github.com/404answernotfound/browsir/utils"
+)
+
+func TestMenuSelect(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("BROWSIR_HOME", dir)
+	utils.SetStores(
+		storage.NewMemoryStore(map[string]string{"wiki": "wiki.example.com"}),
+		storage.NewMemoryStore(map[string]storage.Link{"https://go.dev": {Categories: []string{"go"}}}),
+	)
+	utils.SetHistory(storage.NewMemoryStore(map[string]time.Time{}))
+	t.Cleanup(func() { utils.SetStores(nil, nil); utils.SetHistory(nil) })
+
+	// Saves its entries, then prints $MENU_PICK, or $MENU_PROFILE when asked
+	// for the profile, closing without a pick when it is empty
+	launcher := filepath.Join(dir, "launcher")
+	script := `#!/bin/sh
+cat > "` + dir + `/$2.in"
+case "$2" in
+profile) pick="$MENU_PROFILE" ;;
+*) pick="$MENU_PICK" ;;
+esac
+[ -n "$pick" ] || exit 1
+printf '%s\n' "$pick"
+`
+	if err := os.WriteFile(launcher, []byte(script), 0755); err != nil {
+		t.Fatalf("Error writing the launcher: %v", err)
+	}
+
+	work := config.Profile{Name: "work", Description: "Work"}
+	personal := config.Profile{Name: "personal", Description: "Personal"}
+	c := Command{config: config.Config{
+		Profiles:       []config.Profile{personal, work},
+		DefaultProfile: "work",
+		Shortcuts:      map[string]string{"mail": "mail.example.com", "gh": "github.com/{repo}"},
+		ShortcutModes:  map[string]config.LaunchMode{"mail": {App: true}},
+		Menu:           config.Menu{Launcher: "stub", Launchers: map[string]string{"stub": launcher + " -p {prompt}"}},
+	}}
+
+	tcs := []struct {
+		name    string
+		pick    string
+		profile string
+		flag    string
+		want    menuChoice
+		wantErr error
+	}{
+		{"Test shortcut", "shortcut mail  mail.example.com", "personal  - Personal", "",
+			menuChoice{Launches: []launchGroup{{URLs: []string{"mail.example.com"}, Options: utils.LaunchOptions{App: true}}}, Profile: personal}, nil},
+		{"Test link", "link     https://go.dev  (go)", "work         - Work", "",
+			menuChoice{Launches: []launchGroup{{URLs: []string{"https://go.dev"}}}, Profile: work}, nil},
+		{"Test profile flag", "shortcut wiki  wiki.example.com  (local)", "", "personal",
+			menuChoice{Launches: []launchGroup{{URLs: []string{"wiki.example.com"}}}, Profile: personal}, nil},
+		{"Test free text shortcut", "gh cobra", "work", "",
+			menuChoice{Launches: []launchGroup{{URLs: []string{"github.com/cobra"}}}, Profile: work}, nil},
+		{"Test free text modes", "mail gh cobra", "work", "",
+			menuChoice{Launches: []launchGroup{
+				{URLs: []string{"mail.example.com"}, Options: utils.LaunchOptions{App: true}},
+				{URLs: []string{"github.com/cobra"}},
+			}, Profile: work}, nil},
+		{"Test free text search", "how to close vim", "work", "",
+			menuChoice{Query: "how to close vim", Profile: work}, nil},
+		{"Test nothing picked", "", "", "", menuChoice{}, utils.ErrNothingPicked},
+		{"Test no profile picked", "mail", "", "", menuChoice{}, utils.ErrNothingPicked},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("MENU_PICK", tc.pick)
+			t.Setenv("MENU_PROFILE", tc.profile)
+			got, err := c.menuSelect("", tc.flag)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tc.wantErr)
+			}
+			if err == nil && !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+
+	entries, _ := os.ReadFile(filepath.Join(dir, "browsir.in"))
+	if !strings.Contains(string(entries), "shortcut gh  github.com/{repo}\n") {
+		t.Errorf("got entries %q, want the gh shortcut", entries)
+	}
+	profiles, _ := os.ReadFile(filepath.Join(dir, "profile.in"))
+	if !strings.HasPrefix(string(profiles), "work ") {
+		t.Errorf("got profiles %q, want default_profile first", profiles)
+	}
+
+	if _, err := c.menuSelect("rofi", ""); err == nil {
+		t.Errorf("got no error for a launcher missing from the config")
+	}
+}
@@ -0,0 +1,360 @@
+package browsir
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/404answernotfound/browsir/config"
+	"github.com/404answernotfound/browsir/utils"
+)
+
+// recentInPicker is the number of recent URLs listed by the picker.
+const recentInPicker = 20
+
+// pick finds a shortcut, link or recent URL with a full-screen fuzzy finder,
+// and opens it, in a private window, or copies it. Without a terminal it
+// asks for the number of the entry instead.
+func (c Command) pick(ctx *Context) error {
+	links, err := utils.LoadLinks()
+	if err != nil {
+		return err
+	}
+	c.previewCache().FillTitles(links)
+	items := utils.PickItems(c.config.Shortcuts, utils.LoadLocalShortcuts(), links, utils.RecentURLs(recentInPicker))
+	if len(items) == 0 {
+		return fmt.Errorf("no shortcuts, links or recent URLs to pick from")
+	}
+
+	profileName := ctx.String("profile")
+	if _, ok := c.config.FindProfile(profileName); profileName != "" && !ok {
+		return fmt.Errorf("unknown profile: %s", profileName)
+	}
+	if profileName == "" {
+		profileName = c.config.DefaultProfile
+	}
+	profile := 0
+	for i, p := range c.config.Profiles {
+		if p.Name == profileName {
+			profile = i
+		}
+	}
+
+	p := newPicker(items, c.config.Profiles, profile, strings.Join(ctx.Args, " "))
+	if !utils.IsTerminal(os.Stdout) {
+		return c.pickPrompt(p)
+	}
+	term, err := utils.OpenTerminal()
+	if err != nil {
+		return c.pickPrompt(p)
+	}
+
+	action, err := p.run(term, os.Stdout, term.Size)
+	if closeErr := term.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return c.pickDone(p, action)
+}
+
+// pickPrompt is the picker without a terminal: a numbered list of the
+// entries matching the query.
+func (c Command) pickPrompt(p *picker) error {
+	if len(p.matches) == 0 {
+		return fmt.Errorf("nothing matches %s", string(p.query))
+	}
+	options := make([]string, 0, len(p.matches))
+	for _, i := range p.matches {
+		options = append(options, pickLine(p.items[i]))
+	}
+	choice, err := utils.PromptChoice("Which one do you want to open?", options)
+	if err != nil {
+		return err
+	}
+	p.cursor = choice
+	return c.pickDone(p, pickOpen)
+}
+
+// pickDone carries out what was chosen in the picker.
+func (c Command) pickDone(p *picker, action pickAction) error {
+	item, ok := p.selected()
+	if !ok || action == pickQuit {
+		return nil
+	}
+
+	url, err := pickedURL(item)
+	if err != nil {
+		return err
+	}
+
+	if action == pickCopy {
+		if err := utils.WriteClipboard(url); err != nil {
+			return err
+		}
+		fmt.Printf("Copied %s\n", url)
+		return nil
+	}
+
+	var profile config.Profile
+	if len(p.profiles) > 0 {
+		profile = p.profiles[p.profile]
+	}
+	opts := utils.LaunchOptions{Private: action == pickPrivate}
+	if item.Kind == utils.PickShortcut {
+		opts = opts.WithMode(c.config.ShortcutModes[item.Name])
+	}
+	return utils.OpenURLs(c.config.BrowserName, profile, []string{url}, opts)
+}
+
+// pickedURL is the URL of an item, templates taking their defaults.
+func pickedURL(item utils.PickItem) (string, error) {
+	if !utils.IsTemplate(item.URL) {
+		return item.URL, nil
+	}
+	url, err := utils.ExpandTemplate(item.URL, nil)
+	if err != nil {
+		return "", fmt.Errorf("%v, use browsir <profile> %s <args>", err, item.Name)
+	}
+	return url, nil
+}
+
+type pickAction int
+
+const (
+	pickNone pickAction = iota
+	pickOpen
+	pickPrivate
+	pickCopy
+	pickQuit
+)
+
+// picker is the state of the fuzzy finder: the query, the matching items and
+// the selected one, and the profile they open in.
+type picker struct {
+	items    []utils.PickItem
+	profiles []config.Profile
+	profile  int
+	query    []rune
+	matches  []int // indexes of the items matching the query, best first
+	cursor   int   // in matches
+	top      int   // first match shown
+}
+
+func newPicker(items []utils.PickItem, profiles []config.Profile, profile int, query string) *picker {
+	p := &picker{items: items, profiles: profiles, profile: profile, query: []rune(query)}
+	p.filter()
+	return p
+}
+
+func (p *picker) filter() {
+	p.matches = utils.FilterPickItems(p.items, string(p.query))
+	p.cursor, p.top = 0, 0
+}
+
+func (p *picker) selected() (utils.PickItem, bool) {
+	if p.cursor < 0 || p.cursor >= len(p.matches) {
+		return utils.PickItem{}, false
+	}
+	return p.items[p.matches[p.cursor]], true
+}
+
+// run shows the picker until an action is picked, reading keys from in and
+// drawing on out.
+func (p *picker) run(in io.Reader, out io.Writer, size func() (int, int)) (pickAction, error) {
+	keys := bufio.NewReader(in)
+	for {
+		rows, cols := size()
+		var screen strings.Builder
+		p.render(&screen, rows, cols)
+		if _, err := io.WriteString(out, screen.String()); err != nil {
+			return pickQuit, err
+		}
+
+		k, err := readKey(keys)
+		if err != nil {
+			return pickQuit, err
+		}
+		if action := p.handle(k, rows); action != pickNone {
+			return action, nil
+		}
+	}
+}
+
+// handle applies a key, returning the action it picks, if any.
+func (p *picker) handle(k key, rows int) pickAction {
+	page := max(rows-4, 1)
+	switch k.name {
+	case "enter":
+		return pickOpen
+	case "ctrl-p":
+		return pickPrivate
+	case "ctrl-y":
+		return pickCopy
+	case "esc", "ctrl-c":
+		return pickQuit
+	case "up", "ctrl-k":
+		p.move(-1, page)
+	case "down", "ctrl-j", "ctrl-n":
+		p.move(1, page)
+	case "pgup":
+		p.move(-page, page)
+	case "pgdn":
+		p.move(page, page)
+	case "tab":
+		if len(p.profiles) > 0 {
+			p.profile = (p.profile + 1) % len(p.profiles)
+		}
+	case "backtab":
+		if len(p.profiles) > 0 {
+			p.profile = (p.profile + len(p.profiles) - 1) % len(p.profiles)
+		}
+	case "backspace":
+		if len(p.query) > 0 {
+			p.query = p.query[:len(p.query)-1]
+			p.filter()
+		}
+	case "ctrl-u":
+		p.query = nil
+		p.filter()
+	case "":
+		if unicode.IsPrint(k.r) {
+			p.query = append(p.query, k.r)
+			p.filter()
+		}
+	}
+	return pickNone
+}
+
+func (p *picker) move(delta, page int) {
+	p.cursor = min(max(p.cursor+delta, 0), max(len(p.matches)-1, 0))
+	if p.cursor < p.top {
+		p.top = p.cursor
+	}
+	if p.cursor >= p.top+page {
+		p.top = p.cursor - page + 1
+	}
+}
+
+// pickLine describes an item on one line.
+func pickLine(item utils.PickItem) string {
+	line := fmt.Sprintf("%-8s %s", item.Kind, item.Name)
+	if item.URL != item.Name {
+		line += "  " + item.URL
+	}
+	if item.Detail != "" {
+		line += "  (" + item.Detail + ")"
+	}
+	return line
+}
+
+// render draws the whole screen: the profile, the query, the matches and
+// the keys, leaving the cursor after the query.
+func (p *picker) render(w io.Writer, rows, cols int) {
+	page := max(rows-4, 1)
+	fmt.Fprint(w, "\x1b[H\x1b[2J")
+
+	profile := "(none)"
+	if len(p.profiles) > 0 {
+		profile = p.profiles[p.profile].Name
+	}
+	header := fmt.Sprintf("browsir pick  profile: %s  %d/%d", profile, len(p.matches), len(p.items))
+	fmt.Fprintf(w, "\x1b[1m%s\x1b[0m\r\n", truncate(header, cols))
+	fmt.Fprintf(w, "> %s\r\n", truncate(string(p.query), cols-2))
+
+	for i := p.top; i < len(p.matches) && i < p.top+page; i++ {
+		line := truncate(pickLine(p.items[p.matches[i]]), cols-2)
+		if i == p.cursor {
+			fmt.Fprintf(w, "\x1b[7m> %s\x1b[0m\r\n", line)
+		} else {
+			fmt.Fprintf(w, "  %s\r\n", line)
+		}
+	}
+
+	help := "Enter open · Ctrl-P private · Ctrl-Y copy · Tab profile · Esc quit"
+	fmt.Fprintf(w, "\x1b[%d;1H\x1b[2m%s\x1b[0m", rows, truncate(help, cols))
+	fmt.Fprintf(w, "\x1b[2;%dH", min(len(p.query)+3, cols))
+}
+
+// truncate cuts s to n runes.
+func truncate(s string, n int) string {
+	if n <= 0 {
+		return ""
+	}
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
+
+// key is a key typed in the terminal: a character, or a named key.
+type key struct {
+	r    rune
+	name string
+}
+
+var controlKeys = map[byte]string{
+	0x03: "ctrl-c",
+	0x08: "backspace",
+	0x09: "tab",
+	0x0a: "ctrl-j",
+	0x0b: "ctrl-k",
+	0x0d: "enter",
+	0x0e: "ctrl-n",
+	0x10: "ctrl-p",
+	0x15: "ctrl-u",
+	0x19: "ctrl-y",
+	0x7f: "backspace",
+}
+
+var escapeKeys = map[string]string{
+	"[A":  "up",
+	"[B":  "down",
+	"OA":  "up",
+	"OB":  "down",
+	"[Z":  "backtab",
+	"[5~": "pgup",
+	"[6~": "pgdn",
+}
+
+// readKey reads a key, decoding the escape sequences of the arrow keys.
+// Escape alone is told apart from a sequence by what was typed with it.
+func readKey(r *bufio.Reader) (key, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return key{}, err
+	}
+	if name, ok := controlKeys[b]; ok {
+		return key{name: name}, nil
+	}
+	if b == 0x1b {
+		if r.Buffered() == 0 {
+			return key{name: "esc"}, nil
+		}
+		// ESC [ or ESC O, parameters, then a final byte from @ to ~
+		intro, _ := r.ReadByte()
+		seq := []byte{intro}
+		for (intro == '[' || intro == 'O') && r.Buffered() > 0 {
+			c, _ := r.ReadByte()
+			seq = append(seq, c)
+			if c >= 0x40 && c <= 0x7e {
+				break
+			}
+		}
+		return key{name: escapeKeys[string(seq)], r: -1}, nil
+	}
+	if b < 0x20 {
+		return key{r: -1}, nil
+	}
+
+	if err := r.UnreadByte(); err != nil {
+		return key{}, err
+	}
+	c, _, err := r.ReadRune()
+	return key{r: c}, err
+}
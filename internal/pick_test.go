@@ -0,0 +1,100 @@
+package browsir
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+
+	"github.com/404answernotfound/browsir/config"
+	"github.com/404answernotfound/browsir/storage"
+	"github.com/404answernotfound/browsir/utils"
+)
+
+func TestPicker(t *testing.T) {
+	items := utils.PickItems(
+		map[string]string{"mail": "gmail.com", "gh": "github.com"},
+		map[string]string{"gh": "github.com/acme"},
+		map[string]storage.Link{
+			"https://go.dev":      {Categories: []string{"go"}, Title: "The Go language"},
+			"https://example.com": {Categories: []string{"test"}},
+		},
+		[]string{"https://example.com"},
+	)
+	profiles := []config.Profile{{Name: "personal"}, {Name: "work"}}
+
+	t.Run("Test items", func(t *testing.T) {
+		var got []string
+		for _, item := range items {
+			got = append(got, item.Kind+" "+item.Name+" "+item.Detail)
+		}
+		want := []string{"recent https://example.com test", "shortcut gh local", "shortcut mail ", "link https://go.dev go The Go language"}
+		if strings.Join(got, "|") != strings.Join(want, "|") {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	})
+
+	t.Run("Test typing filters and ranks", func(t *testing.T) {
+		p := newPicker(items, profiles, 0, "")
+		for _, k := range []key{{r: 'g'}, {r: 'o'}, {r: 'l'}} {
+			p.handle(k, 24)
+		}
+		if item, _ := p.selected(); item.Name != "https://go.dev" || len(p.matches) != 2 {
+			t.Errorf("got %v matching %q, selected %+v", len(p.matches), string(p.query), item)
+		}
+		p.handle(key{name: "backspace"}, 24)
+		p.handle(key{name: "backspace"}, 24)
+		if len(p.matches) != 3 {
+			t.Errorf("got %v matches for %q, want 3", len(p.matches), string(p.query))
+		}
+	})
+
+	t.Run("Test moving, switching profiles and actions", func(t *testing.T) {
+		p := newPicker(items, profiles, 0, "")
+		p.handle(key{name: "down"}, 24)
+		p.handle(key{name: "down"}, 24)
+		p.handle(key{name: "up"}, 24)
+		if item, _ := p.selected(); item.Name != "gh" {
+			t.Errorf("got %+v selected, want gh", item)
+		}
+		p.handle(key{name: "pgdn"}, 24)
+		if item, _ := p.selected(); item.Name != "https://go.dev" {
+			t.Errorf("got %+v selected, want the last item", item)
+		}
+		p.handle(key{name: "backtab"}, 24)
+		if p.profile != 1 {
+			t.Errorf("got profile %v, want 1", p.profile)
+		}
+		for name, want := range map[string]pickAction{"enter": pickOpen, "ctrl-p": pickPrivate, "ctrl-y": pickCopy, "esc": pickQuit} {
+			if got := p.handle(key{name: name}, 24); got != want {
+				t.Errorf("%s: got action %v, want %v", name, got, want)
+			}
+		}
+	})
+
+	t.Run("Test run scrolls and renders", func(t *testing.T) {
+		p := newPicker(items, profiles, 1, "")
+		var screen strings.Builder
+		action, err := p.run(strings.NewReader("\x1b[B\x1b[B\x1b[B\x10"), &screen, func() (int, int) { return 6, 40 })
+		if err != nil || action != pickPrivate {
+			t.Fatalf("got %v, %v, want a private open", action, err)
+		}
+		if p.top != 2 || p.cursor != 3 {
+			t.Errorf("got top %v and cursor %v, want 2 and 3", p.top, p.cursor)
+		}
+		last := screen.String()[strings.LastIndex(screen.String(), "\x1b[H"):]
+		if !strings.Contains(last, "profile: work  4/4") || !strings.Contains(last, "\x1b[7m> link     https://go.dev  (go The Go la\x1b[0m") {
+			t.Errorf("got screen %q", last)
+		}
+	})
+}
+
+func TestReadKey(t *testing.T) {
+	r := bufio.NewReader(strings.NewReader("aé\r\x7f\x1b[A\x1bOB\x1b[Z\x1b[6~\x19"))
+	want := []key{{r: 'a'}, {r: 'é'}, {name: "enter"}, {name: "backspace"}, {name: "up", r: -1}, {name: "down", r: -1}, {name: "backtab", r: -1}, {name: "pgdn", r: -1}, {name: "ctrl-y"}}
+	for _, w := range want {
+		got, err := readKey(r)
+		if err != nil || got != w {
+			t.Errorf("got %+v, %v, want %+v", got, err, w)
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package browsir
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/404answernotfound/browsir/config"
+	"github.com/404answernotfound/browsir/utils"
+)
+
+// root handles "browsir <profile> [url|shortcut [template args...]]..." and
+// the informational flags, keeping the original command line working.
+func (c Command) root(ctx *Context) error {
+	localShortcuts := utils.LoadLocalShortcuts()
+
+	// This is to allow multiple informational flags to be handled
+	informational := false
+	if ctx.Bool("help") {
+		if err := c.usage(ctx, localShortcuts); err != nil {
+			return err
+		}
+		informational = true
+	}
+	if ctx.Bool("version") {
+		if version := os.Getenv("BROWSIR_VERSION"); version != "" {
+			fmt.Println("  browsir v" + version)
+		} else {
+			fmt.Println("  browsir version not set")
+		}
+		informational = true
+	}
+	if ctx.Bool("list-shortcuts") {
+		if ctx.IsSet("output") {
+			if err := printListing(ctx, utils.ShortcutListing(c.config.Shortcuts, localShortcuts)); err != nil {
+				return err
+			}
+		} else {
+			utils.PrintLocalShortcuts(c.config.Shortcuts)
+		}
+		informational = true
+	}
+	if ctx.Bool("profiles") {
+		if err := c.profiles(ctx); err != nil {
+			return err
+		}
+		informational = true
+	}
+	if informational {
+		return nil
+	}
+
+	query := ctx.String("query")
+	if len(ctx.Args) == 0 && query == "" {
+		return c.usage(ctx, localShortcuts)
+	}
+
+	var profileName string
+	if len(ctx.Args) > 0 {
+		profileName = ctx.Args[0]
+	}
+	selectedProfile, found := c.config.FindProfile(profileName)
+
+	if query != "" {
+		if !found && profileName != "" {
+			return fmt.Errorf("unknown profile: %s", profileName)
+		}
+		fmt.Println("Searching...")
+		return c.searchWith(selectedProfile, ctx.String("search-engine"), query)
+	}
+
+	if !found {
+		fmt.Fprintf(os.Stderr, "Error: unknown profile: %s\n", profileName)
+		utils.PrintUsage(c.config.Profiles, c.config.Shortcuts, localShortcuts)
+		return errQuiet
+	}
+
+	args, input, err := inputURLs(ctx.Args[1:], os.Stdin, ctx.Bool("clipboard"))
+	if err != nil {
+		return err
+	}
+
+	targets, unknown, err := utils.ResolveTargets(args, c.config.Shortcuts, localShortcuts)
+	if err != nil {
+		return err
+	}
+	if len(unknown) == 1 && len(args) == 1 && len(input) == 0 {
+		return c.unknownShortcut(unknown[0], localShortcuts)
+	}
+	if len(unknown) > 0 {
+		return c.unknownShortcuts(unknown, localShortcuts)
+	}
+
+	for _, url := range input {
+		targets = append(targets, utils.Target{Name: url, URL: url})
+	}
+	var urls []string
+	for _, target := range targets {
+		urls = append(urls, target.URL)
+	}
+	opts, err := c.launchOptions(ctx)
+	if err != nil {
+		return err
+	}
+
+	if len(input) > 0 && !ctx.Bool("yes") {
+		stdinUsed := len(args) < len(ctx.Args)-1
+		if ok, err := c.confirmURLs(urls, stdinUsed); !ok || err != nil {
+			return err
+		}
+	}
+	return c.openLaunches(selectedProfile, c.launchGroups(opts, targets))
+}
+
+// usage prints the profiles, the shortcuts and the commands, or only the
+// profiles and the shortcuts with --output.
+func (c Command) usage(ctx *Context, localShortcuts map[string]string) error {
+	if ctx.IsSet("output") {
+		return printListing(ctx, utils.Usage{
+			Profiles:  utils.ProfileListing(c.config.Profiles),
+			Shortcuts: utils.ShortcutListing(c.config.Shortcuts, localShortcuts),
+		})
+	}
+	utils.PrintUsage(c.config.Profiles, c.config.Shortcuts, localShortcuts)
+	return nil
+}
+
+// printListing prints listing in the format given with --output.
+func printListing(ctx *Context, listing utils.Listing) error {
+	format := ctx.String("output")
+	if !utils.Contains(utils.OutputFormats, format) {
+		return usageErrorf(ctx.Cmd, "unknown output format: %s, use %s", format, strings.Join(utils.OutputFormats, ", "))
+	}
+	return utils.WriteListing(os.Stdout, format, listing)
+}
+
+// unknownShortcut suggests similar shortcuts, or offers to save a new one.
+func (c Command) unknownShortcut(shortcut string, localShortcuts map[string]string) error {
+	// Check for similar shortcuts
+	similar := utils.FindSimilarShortcuts(shortcut, c.config.Shortcuts, localShortcuts)
+	if len(similar) > 0 {
+		fmt.Printf("\033[33mDid you mean one of these shortcuts?\033[0m\n")
+		for _, s := range similar {
+			if u, exists := localShortcuts[s]; exists {
+				fmt.Printf("\033[36m  %s\033[0m -> %s (local)\n", s, u)
+			} else {
+				fmt.Printf("\033[36m  %s\033[0m -> %s\n", s, c.config.Shortcuts[s])
+			}
+		}
+		return errQuiet
+	}
+
+	// If no similar shortcuts found, ask if they want to save it
+	if !utils.PromptYesNo("Would you like to save this as a shortcut?") {
+		fmt.Println("\033[32mTip: You can add shortcuts in your .browsir.yml config file or local shortcuts file\033[0m")
+		return errQuiet
+	}
+
+	fmt.Print("Enter the website URL: ")
+	reader := bufio.NewReader(os.Stdin)
+	websiteURL, _ := reader.ReadString('\n')
+	websiteURL = strings.TrimSpace(websiteURL)
+
+	if err := utils.SaveLocalShortcut(shortcut, websiteURL); err != nil {
+		return fmt.Errorf("error saving shortcut: %v", err)
+	}
+	fmt.Printf("\033[32mShortcut saved: %s -> %s\033[0m\n", shortcut, websiteURL)
+	return nil
+}
+
+// unknownShortcuts reports every target that is neither a URL nor a
+// shortcut, with the shortcuts they may be a typo of.
+func (c Command) unknownShortcuts(unknown []string, localShortcuts map[string]string) error {
+	fmt.Fprintln(os.Stderr, "Error: unknown shortcuts, nothing was opened:")
+	for _, shortcut := range unknown {
+		similar := utils.FindSimilarShortcuts(shortcut, c.config.Shortcuts, localShortcuts)
+		if len(similar) > 0 {
+			fmt.Fprintf(os.Stderr, "  %s (did you mean %s?)\n", shortcut, strings.Join(similar, ", "))
+		} else {
+			fmt.Fprintf(os.Stderr, "  %s\n", shortcut)
+		}
+	}
+	return errQuiet
+}
+
+// search searches the web with the words given on the command line.
+func (c Command) search(ctx *Context) error {
+	var profile config.Profile
+	name := ctx.String("profile")
+	if name == "" {
+		name = c.config.DefaultProfile
+	}
+	if name != "" {
+		var ok bool
+		if profile, ok = c.config.FindProfile(name); !ok {
+			return fmt.Errorf("unknown profile: %s", name)
+		}
+	}
+
+	return c.searchWith(profile, ctx.String("engine"), strings.Join(ctx.Args, " "))
+}
+
+func (c Command) searchWith(profile config.Profile, searchEngine string, query string) error {
+	if searchEngine == "" {
+		searchEngine = c.config.DefaultSearchEngine
+	}
+	return utils.Search(c.config.BrowserName, profile, c.config.SearchEngines, searchEngine, query)
+}
@@ -0,0 +1,177 @@
+package browsir
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/404answernotfound/browsir/config"
+	"github.com/404answernotfound/browsir/storage"
+	"github.com/404answernotfound/browsir/utils"
+)
+
+// session opens a session, or lists them when no name is given.
+func (c Command) session(ctx *Context) error {
+	if len(ctx.Args) == 0 {
+		return c.listSessions(ctx)
+	}
+	return c.openSession(ctx.Args[0])
+}
+
+// openSession opens the URLs of a session, the way its mode says.
+func (c Command) openSession(name string) error {
+	profile, launches, err := c.planSession(name)
+	if err != nil {
+		return err
+	}
+	for _, launch := range launches {
+		if err := utils.CheckLaunch(c.config.BrowserName, profile, launch.URLs, launch.Options); err != nil {
+			return err
+		}
+	}
+	for _, launch := range launches {
+		time.Sleep(launch.Delay)
+		if err := utils.OpenURLs(c.config.BrowserName, profile, launch.URLs, launch.Options); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sessionLaunch is one start of the browser while opening a session.
+type sessionLaunch struct {
+	URLs    []string
+	Options utils.LaunchOptions
+	Delay   time.Duration // waited before the launch
+}
+
+// planSession resolves the profile and every entry of a session, so that
+// nothing opens when one is wrong, and splits its URLs into launches. An
+// entry can be a template with its arguments, as in "jira PROJ-1", or have
+// them in the entries that follow, as saved by "session save". Shortcuts
+// open in their shortcut_modes, apart from the other URLs.
+func (c Command) planSession(name string) (config.Profile, []sessionLaunch, error) {
+	session, ok := c.config.Sessions[name]
+	if !ok {
+		return config.Profile{}, nil, fmt.Errorf("unknown session: %s", name)
+	}
+
+	profile, err := c.sessionProfile(session)
+	if err != nil {
+		return config.Profile{}, nil, err
+	}
+
+	var args []string
+	for _, entry := range session.URLs {
+		args = append(args, strings.Fields(entry)...)
+	}
+	targets, unknown, err := utils.ResolveTargets(args, c.config.Shortcuts, utils.LoadLocalShortcuts())
+	if err != nil {
+		return config.Profile{}, nil, fmt.Errorf("session %s has an invalid entry: %v", name, err)
+	}
+	if len(unknown) > 0 {
+		return config.Profile{}, nil, fmt.Errorf("session %s has unknown shortcuts: %s", name, strings.Join(unknown, ", "))
+	}
+	if len(targets) == 0 {
+		return config.Profile{}, nil, fmt.Errorf("session %s has no urls", name)
+	}
+	// grouped opens the targets in as few launches as their modes allow, the
+	// first one after delay
+	grouped := func(targets []utils.Target, opts utils.LaunchOptions, delay time.Duration) []sessionLaunch {
+		var launches []sessionLaunch
+		for _, group := range c.launchGroups(opts, targets) {
+			launches = append(launches, sessionLaunch{URLs: group.URLs, Options: group.Options, Delay: delay})
+			delay = 0
+		}
+		return launches
+	}
+	// each opens every target with its own launch, delay apart
+	each := func(targets []utils.Target, opts utils.LaunchOptions, first time.Duration) []sessionLaunch {
+		var launches []sessionLaunch
+		for i, target := range targets {
+			delay := session.Delay
+			if i == 0 {
+				delay = first
+			}
+			launches = append(launches, grouped([]utils.Target{target}, opts, delay)...)
+		}
+		return launches
+	}
+
+	newWindow := utils.LaunchOptions{NewWindow: true}
+	switch session.Mode {
+	case "", config.SessionWindow:
+		if session.Delay == 0 {
+			return profile, grouped(targets, newWindow, 0), nil
+		}
+		// Open the window with the first url, then add the others as tabs
+		launches := grouped(targets[:1], newWindow, 0)
+		return profile, append(launches, each(targets[1:], utils.LaunchOptions{}, session.Delay)...), nil
+	case config.SessionWindows:
+		return profile, each(targets, newWindow, 0), nil
+	case config.SessionTabs:
+		return profile, each(targets, utils.LaunchOptions{}, 0), nil
+	default:
+		return config.Profile{}, nil, fmt.Errorf("session %s has unknown mode %s, use window, windows or tabs", name, session.Mode)
+	}
+}
+
+// sessionProfile returns the profile of a session, default_profile or else
+// the first profile when the session does not set one.
+func (c Command) sessionProfile(session config.Session) (config.Profile, error) {
+	name := session.Profile
+	if name == "" {
+		name = c.config.DefaultProfile
+	}
+	if name == "" {
+		return c.config.Profiles[0], nil
+	}
+	profile, ok := c.config.FindProfile(name)
+	if !ok {
+		return config.Profile{}, fmt.Errorf("unknown profile: %s", name)
+	}
+	return profile, nil
+}
+
+// saveSession stores the urls given on the command line as a session.
+func (c Command) saveSession(ctx *Context) error {
+	if ctx.Cmd.parent.Find(ctx.Args[0]) != nil {
+		return usageErrorf(ctx.Cmd, "%s is a session command, pick another name", ctx.Args[0])
+	}
+	session := config.Session{
+		Profile: ctx.String("profile"),
+		URLs:    ctx.Args[1:],
+		Mode:    ctx.String("mode"),
+	}
+	if session.Profile != "" {
+		if _, ok := c.config.FindProfile(session.Profile); !ok {
+			return fmt.Errorf("unknown profile: %s", session.Profile)
+		}
+	}
+	if delay := ctx.String("delay"); delay != "" {
+		d, err := time.ParseDuration(delay)
+		if err != nil {
+			return fmt.Errorf("invalid delay %s: %v", delay, err)
+		}
+		session.Delay = d
+	}
+
+	if err := config.SaveSession(ctx.Args[0], session); err != nil {
+		return err
+	}
+	fmt.Printf("Session %s correctly saved with %d urls\n", ctx.Args[0], len(session.URLs))
+	return nil
+}
+
+func (c Command) listSessions(ctx *Context) error {
+	if ctx.IsSet("output") {
+		return printListing(ctx, utils.SessionListing(c.config.Sessions))
+	}
+
+	fmt.Println("\nSessions:")
+	for _, name := range storage.Keys(c.config.Sessions) {
+		session := c.config.Sessions[name]
+		fmt.Printf("  %-12s - %s\n", name, strings.Join(session.URLs, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package browsir
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/404answernotfound/browsir/config"
+	"github.com/404answernotfound/browsir/storage"
+	"github.com/404answernotfound/browsir/utils"
+)
+
+func TestPlanSession(t *testing.T) {
+	utils.SetStores(storage.NewMemoryStore(map[string]string{"wiki": "wiki.example.com"}), storage.NewMemoryStore(map[string]storage.Link{}))
+	t.Cleanup(func() { utils.SetStores(nil, nil) })
+
+	personal := config.Profile{Name: "personal"}
+	work := config.Profile{Name: "work"}
+	c := Command{config: config.Config{
+		Profiles:       []config.Profile{personal, work},
+		DefaultProfile: "work",
+		Shortcuts: map[string]string{
+			"mail": "mail.example.com",
+			"jira": "jira.example.com/browse/{1}",
+			"gh":   "github.com/{owner}/{repo:browsir}",
+			"bank": "bank.example.com",
+		},
+		ShortcutModes: map[string]config.LaunchMode{"bank": {Private: true}},
+		Sessions: map[string]config.Session{
+			"standup":  {Profile: "personal", URLs: []string{"mail", "jira PROJ-1", "wiki"}},
+			"saved":    {URLs: []string{"gh", "acme", "api", "mail"}, Mode: config.SessionTabs},
+			"windows":  {URLs: []string{"mail", "gh acme"}, Mode: config.SessionWindows, Delay: time.Second},
+			"delayed":  {URLs: []string{"mail", "wiki", "example.com"}, Delay: time.Second},
+			"save":     {URLs: []string{"mail"}},
+			"private":  {URLs: []string{"mail", "bank", "wiki"}},
+			"privtabs": {URLs: []string{"bank", "mail"}, Mode: config.SessionTabs},
+
+			"badprofile": {Profile: "nope", URLs: []string{"mail"}},
+			"unknown":    {URLs: []string{"mail", "nope"}},
+			"missing":    {URLs: []string{"jira"}},
+			"badmode":    {URLs: []string{"mail"}, Mode: "popup"},
+			"empty":      {},
+		},
+	}}
+	newWindow := utils.LaunchOptions{NewWindow: true}
+
+	tcs := []struct {
+		name        string
+		wantProfile config.Profile
+		want        []sessionLaunch
+		wantErr     bool
+	}{
+		{"standup", personal, []sessionLaunch{
+			{URLs: []string{"mail.example.com", "jira.example.com/browse/PROJ-1", "wiki.example.com"}, Options: newWindow},
+		}, false},
+		{"saved", work, []sessionLaunch{
+			{URLs: []string{"github.com/acme/api"}},
+			{URLs: []string{"mail.example.com"}},
+		}, false},
+		{"windows", work, []sessionLaunch{
+			{URLs: []string{"mail.example.com"}, Options: newWindow},
+			{URLs: []string{"github.com/acme/browsir"}, Options: newWindow, Delay: time.Second},
+		}, false},
+		{"delayed", work, []sessionLaunch{
+			{URLs: []string{"mail.example.com"}, Options: newWindow},
+			{URLs: []string{"wiki.example.com"}, Delay: time.Second},
+			{URLs: []string{"example.com"}, Delay: time.Second},
+		}, false},
+		{"save", work, []sessionLaunch{{URLs: []string{"mail.example.com"}, Options: newWindow}}, false},
+		{"private", work, []sessionLaunch{
+			{URLs: []string{"mail.example.com", "wiki.example.com"}, Options: newWindow},
+			{URLs: []string{"bank.example.com"}, Options: utils.LaunchOptions{NewWindow: true, Private: true}},
+		}, false},
+		{"privtabs", work, []sessionLaunch{
+			{URLs: []string{"bank.example.com"}, Options: utils.LaunchOptions{Private: true}},
+			{URLs: []string{"mail.example.com"}},
+		}, false},
+		{"badprofile", config.Profile{}, nil, true},
+		{"unknown", config.Profile{}, nil, true},
+		{"missing", config.Profile{}, nil, true},
+		{"badmode", config.Profile{}, nil, true},
+		{"empty", config.Profile{}, nil, true},
+		{"nosuchsession", config.Profile{}, nil, true},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			profile, launches, err := c.planSession(tc.name)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tc.wantErr)
+			}
+			if profile != tc.wantProfile || !reflect.DeepEqual(launches, tc.want) {
+				t.Errorf("got %v and %+v, want %v and %+v", profile.Name, launches, tc.wantProfile.Name, tc.want)
+			}
+		})
+	}
+
+	t.Run("Test first profile without default_profile", func(t *testing.T) {
+		c := c
+		c.config.DefaultProfile = ""
+		if profile, _, err := c.planSession("saved"); err != nil || profile != personal {
+			t.Errorf("got %v, %v, want %v", profile.Name, err, personal.Name)
+		}
+	})
+}
+
+func TestSessionCommands(t *testing.T) {
+	root := NewRootCmd(config.Config{})
+	session := root.Find("session")
+	if session.Find("open") == nil {
+		t.Fatalf("got no session open command")
+	}
+
+	for _, name := range []string{"save", "open"} {
+		err := Command{}.saveSession(&Context{Cmd: session.Find("save"), Args: []string{name, "mail"}})
+		var usageErr *UsageError
+		if !errors.As(err, &usageErr) {
+			t.Errorf("got %v saving a session named %s, want a usage error", err, name)
+		}
+	}
+}
+
+func TestListSessions(t *testing.T) {
+	cnf := config.Config{Sessions: map[string]config.Session{
+		"standup": {Profile: "work", URLs: []string{"mail", "jira PROJ-1"}, Delay: 500 * time.Millisecond},
+		"reading": {URLs: []string{"news.ycombinator.com"}, Mode: config.SessionTabs},
+	}}
+
+	var code int
+	out := captureStdout(t, func() {
+		code = NewRootCmd(cnf).Execute([]string{"session", "-o", "json"})
+	})
+	if code != ExitOK {
+		t.Fatalf("got exit code %v, want %v", code, ExitOK)
+	}
+	var got []utils.SessionEntry
+	if err := json.Unmarshal([]byte(out), &got); err != nil {
+		t.Fatalf("got %q, not JSON: %v", out, err)
+	}
+	want := []utils.SessionEntry{
+		{Name: "reading", Mode: config.SessionTabs, URLs: []string{"news.ycombinator.com"}},
+		{Name: "standup", Profile: "work", Mode: config.SessionWindow, Delay: "500ms", URLs: []string{"mail", "jira PROJ-1"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,288 @@
+package storage
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// maxLineSize bounds the lines of the files, link records holding notes.
+const maxLineSize = 1024 * 1024
+
+// FileStore keeps entries in a line based flat file. Lines it does not
+// understand, such as comments, are preserved when the file is rewritten.
+type FileStore[V any] struct {
+	path     string
+	format   Format[V]
+	entries  map[string]V
+	loaded   bool
+	outdated bool // the file lacks the header of a VersionedFormat
+}
+
+func NewFileStore[V any](path string, format Format[V]) *FileStore[V] {
+	return &FileStore[V]{path: path, format: format, entries: make(map[string]V)}
+}
+
+// Path returns the file backing the store.
+func (s *FileStore[V]) Path() string {
+	return s.path
+}
+
+// Load reads the file. Until it is read, Put and Delete fail rather than
+// write over a file they could not read.
+func (s *FileStore[V]) Load() error {
+	s.loaded = false
+
+	f, err := os.Open(s.path)
+	if os.IsNotExist(err) {
+		s.entries = make(map[string]V)
+		s.loaded = true
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var lines []string
+	entries := make(map[string]V)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, maxLineSize)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+		if key, value, ok := s.format.ParseLine(scanner.Text()); ok {
+			entries[key] = value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("error reading %s: %v", s.path, err)
+	}
+	s.entries = entries
+	s.loaded = true
+
+	versioned, ok := s.format.(VersionedFormat[V])
+	s.outdated = ok && len(entries) > 0 && lines[0] != versioned.Header()
+	return nil
+}
+
+// migrate rewrites an outdated file with the header of its format and every
+// entry in the current format, keeping the other lines. It runs before the
+// first write, so that files that can not be written, such as system-wide
+// ones, are still read.
+func (s *FileStore[V]) migrate() error {
+	versioned, ok := s.format.(VersionedFormat[V])
+	if !s.outdated || !ok {
+		return nil
+	}
+
+	header := versioned.Header()
+	first := true
+	_, err := rewriteFile(s.path, func(line string) (string, bool) {
+		if key, value, ok := s.format.ParseLine(line); ok {
+			line = s.format.FormatLine(key, value)
+		}
+		if !first {
+			return line, true
+		}
+		first = false
+		// An older header is replaced, not kept as a comment
+		if strings.TrimRight(line, "0123456789") == strings.TrimRight(header, "0123456789") {
+			return header, true
+		}
+		return header + "\n" + line, true
+	})
+	if err != nil {
+		return fmt.Errorf("error upgrading %s: %v", s.path, err)
+	}
+	s.outdated = false
+	return nil
+}
+
+func (s *FileStore[V]) Get(key string) (V, bool) {
+	if err := s.ensureLoaded(); err != nil {
+		var zero V
+		return zero, false
+	}
+	value, ok := s.entries[key]
+	return value, ok
+}
+
+func (s *FileStore[V]) Put(key string, value V) error {
+	if err := s.ensureLoaded(); err != nil {
+		return err
+	}
+	if err := s.migrate(); err != nil {
+		return err
+	}
+
+	if _, exists := s.entries[key]; exists {
+		line := s.format.FormatLine(key, value)
+		_, err := rewriteFile(s.path, func(l string) (string, bool) {
+			if k, _, ok := s.format.ParseLine(l); ok && k == key {
+				return line, true
+			}
+			return l, true
+		})
+		if err != nil {
+			return err
+		}
+		s.entries[key] = value
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	line := s.format.FormatLine(key, value)
+	if !endsWithNewline(f) {
+		line = "\n" + line
+	}
+	if versioned, ok := s.format.(VersionedFormat[V]); ok && isEmpty(f) {
+		line = versioned.Header() + "\n" + line
+	}
+	if _, err := fmt.Fprintln(f, line); err != nil {
+		return err
+	}
+	s.entries[key] = value
+	return nil
+}
+
+func (s *FileStore[V]) Delete(keys ...string) error {
+	if err := s.ensureLoaded(); err != nil {
+		return err
+	}
+	if err := s.migrate(); err != nil {
+		return err
+	}
+
+	drop := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		drop[k] = true
+	}
+
+	removed, err := rewriteFile(s.path, func(l string) (string, bool) {
+		k, _, ok := s.format.ParseLine(l)
+		return l, !ok || !drop[k]
+	})
+	if os.IsNotExist(err) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if removed == 0 {
+		return ErrNotFound
+	}
+
+	for _, k := range keys {
+		delete(s.entries, k)
+	}
+	return nil
+}
+
+func (s *FileStore[V]) List() map[string]V {
+	if err := s.ensureLoaded(); err != nil {
+		return make(map[string]V)
+	}
+	entries := make(map[string]V, len(s.entries))
+	for k, v := range s.entries {
+		entries[k] = v
+	}
+	return entries
+}
+
+func (s *FileStore[V]) ensureLoaded() error {
+	if s.loaded {
+		return nil
+	}
+	return s.Load()
+}
+
+func isEmpty(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Size() == 0
+}
+
+// endsWithNewline reports whether f is empty or ends with a newline, so that
+// appended lines are not glued to a last line lacking one.
+func endsWithNewline(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil || info.Size() == 0 {
+		return true
+	}
+	last := make([]byte, 1)
+	if _, err := f.ReadAt(last, info.Size()-1); err != nil {
+		return true
+	}
+	return last[0] == '\n'
+}
+
+// rewriteFile copies path into a temporary file next to it, passing every
+// line through edit, and then renames the temporary file over the original
+// so readers never observe a half written file. Lines for which edit returns
+// false are dropped; the number of dropped lines is returned.
+func rewriteFile(path string, edit func(line string) (string, bool)) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	// Create a temp empty file in the same directory, so the rename is atomic
+	tempFile, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return 0, err
+	}
+	tempFilePath := tempFile.Name()
+	defer os.Remove(tempFilePath)
+	defer tempFile.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, maxLineSize)
+	writer := bufio.NewWriter(tempFile)
+
+	removed := 0
+	for scanner.Scan() {
+		line, keep := edit(scanner.Text())
+		if !keep {
+			removed++
+			continue
+		}
+		fmt.Fprintln(writer, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+
+	if err := writer.Flush(); err != nil {
+		return 0, err
+	}
+	if err := tempFile.Chmod(info.Mode().Perm()); err != nil {
+		return 0, err
+	}
+	if err := tempFile.Sync(); err != nil {
+		return 0, err
+	}
+	if err := tempFile.Close(); err != nil {
+		return 0, err
+	}
+
+	// Replace the temp file as the new file
+	if err := os.Rename(tempFilePath, path); err != nil {
+		return 0, err
+	}
+	return removed, nil
+}
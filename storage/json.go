@@ -0,0 +1,157 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// jsonVersion is the version written in the documents of a JSONStore,
+// unless given to NewVersionedJSONStore.
+const jsonVersion = 1
+
+type jsonDocument[V any] struct {
+	Version int          `json:"version"`
+	Entries map[string]V `json:"entries"`
+}
+
+// JSONStore keeps entries in a single JSON document, which is rewritten
+// atomically on every change.
+type JSONStore[V any] struct {
+	path    string
+	version int
+	entries map[string]V
+	loaded  bool
+}
+
+func NewJSONStore[V any](path string) *JSONStore[V] {
+	return NewVersionedJSONStore[V](path, jsonVersion)
+}
+
+// NewVersionedJSONStore returns a store whose documents have the given
+// version. Older documents are rewritten in it by the first write, so V has
+// to decode their entries too.
+func NewVersionedJSONStore[V any](path string, version int) *JSONStore[V] {
+	return &JSONStore[V]{path: path, version: version, entries: make(map[string]V)}
+}
+
+// Path returns the file backing the store.
+func (s *JSONStore[V]) Path() string {
+	return s.path
+}
+
+// Load reads the document. Until it is read and parsed, Put and Delete fail
+// rather than replace a file they could not read with their single entry.
+func (s *JSONStore[V]) Load() error {
+	s.loaded = false
+
+	data, err := os.ReadFile(s.path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	var doc jsonDocument[V]
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return fmt.Errorf("error parsing %s: %v", s.path, err)
+		}
+	}
+	s.entries = doc.Entries
+	if s.entries == nil {
+		s.entries = make(map[string]V)
+	}
+	s.loaded = true
+	return nil
+}
+
+func (s *JSONStore[V]) Get(key string) (V, bool) {
+	if err := s.ensureLoaded(); err != nil {
+		var zero V
+		return zero, false
+	}
+	value, ok := s.entries[key]
+	return value, ok
+}
+
+func (s *JSONStore[V]) Put(key string, value V) error {
+	if err := s.ensureLoaded(); err != nil {
+		return err
+	}
+	s.entries[key] = value
+	return s.save()
+}
+
+func (s *JSONStore[V]) Delete(keys ...string) error {
+	if err := s.ensureLoaded(); err != nil {
+		return err
+	}
+
+	removed := 0
+	for _, k := range keys {
+		if _, ok := s.entries[k]; ok {
+			delete(s.entries, k)
+			removed++
+		}
+	}
+	if removed == 0 {
+		return ErrNotFound
+	}
+	return s.save()
+}
+
+func (s *JSONStore[V]) List() map[string]V {
+	if err := s.ensureLoaded(); err != nil {
+		return make(map[string]V)
+	}
+	entries := make(map[string]V, len(s.entries))
+	for k, v := range s.entries {
+		entries[k] = v
+	}
+	return entries
+}
+
+func (s *JSONStore[V]) ensureLoaded() error {
+	if s.loaded {
+		return nil
+	}
+	return s.Load()
+}
+
+func (s *JSONStore[V]) save() error {
+	data, err := json.MarshalIndent(jsonDocument[V]{Version: s.version, Entries: s.entries}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return WriteFileAtomic(s.path, append(data, '\n'), 0644)
+}
+
+// WriteFileAtomic writes data to a temporary file next to path and renames it
+// over path, creating the parent directory when needed.
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	tempFile, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tempFilePath := tempFile.Name()
+	defer os.Remove(tempFilePath)
+	defer tempFile.Close()
+
+	if _, err := tempFile.Write(data); err != nil {
+		return err
+	}
+	if err := tempFile.Chmod(perm); err != nil {
+		return err
+	}
+	if err := tempFile.Sync(); err != nil {
+		return err
+	}
+	if err := tempFile.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tempFilePath, path)
+}
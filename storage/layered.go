@@ -0,0 +1,67 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// ErrReadOnly is returned by LayeredStore.Delete for entries that only exist
+// in its read-only base store.
+var ErrReadOnly = errors.New("read-only")
+
+// LayeredStore merges a read-only base store, such as machine-wide team
+// defaults, with a writable top store whose entries take precedence.
+// Writes and deletes only ever touch the top store.
+type LayeredStore[V any] struct {
+	base Store[V]
+	top  Store[V]
+	warn io.Writer // where a base store that can not be loaded is reported
+}
+
+func NewLayeredStore[V any](base, top Store[V]) *LayeredStore[V] {
+	return &LayeredStore[V]{base: base, top: top, warn: os.Stderr}
+}
+
+// Load reads both stores. A base store that can not be loaded is reported
+// and left out, so it never hides the entries of the top store.
+func (s *LayeredStore[V]) Load() error {
+	if err := s.base.Load(); err != nil {
+		fmt.Fprintf(s.warn, "Warning: skipping the system-wide entries: %v\n", err)
+	}
+	return s.top.Load()
+}
+
+func (s *LayeredStore[V]) Get(key string) (V, bool) {
+	if value, ok := s.top.Get(key); ok {
+		return value, true
+	}
+	return s.base.Get(key)
+}
+
+func (s *LayeredStore[V]) Put(key string, value V) error {
+	return s.top.Put(key, value)
+}
+
+// Delete removes keys from the top store. Keys only found in the base store
+// can not be removed, and fail with ErrReadOnly before anything is removed.
+func (s *LayeredStore[V]) Delete(keys ...string) error {
+	for _, k := range keys {
+		if _, ok := s.top.Get(k); ok {
+			continue
+		}
+		if _, ok := s.base.Get(k); ok {
+			return fmt.Errorf("%w: %s", ErrReadOnly, k)
+		}
+	}
+	return s.top.Delete(keys...)
+}
+
+func (s *LayeredStore[V]) List() map[string]V {
+	entries := s.base.List()
+	for k, v := range s.top.List() {
+		entries[k] = v
+	}
+	return entries
+}
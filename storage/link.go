@@ -0,0 +1,138 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// LinkVersion is the version of the link records, written in the header of
+// links files and in links.json.
+const LinkVersion = 2
+
+// Link is what is stored about a link, keyed by its URL.
+type Link struct {
+	Categories []string
+	Title      string
+	Note       string
+	Created    time.Time
+	Updated    time.Time
+	LastOpened time.Time
+	OpenCount  int
+}
+
+// linkJSON leaves the unknown times out of the JSON documents.
+type linkJSON struct {
+	Categories []string   `json:"categories,omitempty"`
+	Title      string     `json:"title,omitempty"`
+	Note       string     `json:"note,omitempty"`
+	Created    *time.Time `json:"created,omitempty"`
+	Updated    *time.Time `json:"updated,omitempty"`
+	LastOpened *time.Time `json:"last_opened,omitempty"`
+	OpenCount  int        `json:"open_count,omitempty"`
+}
+
+func (l Link) MarshalJSON() ([]byte, error) {
+	return json.Marshal(linkJSON{
+		Categories: l.Categories,
+		Title:      l.Title,
+		Note:       l.Note,
+		Created:    timeOrNil(l.Created),
+		Updated:    timeOrNil(l.Updated),
+		LastOpened: timeOrNil(l.LastOpened),
+		OpenCount:  l.OpenCount,
+	})
+}
+
+// UnmarshalJSON also reads the version 1 records, which were the categories
+// joined by commas.
+func (l *Link) UnmarshalJSON(data []byte) error {
+	var categories string
+	if err := json.Unmarshal(data, &categories); err == nil {
+		*l = Link{Categories: SplitList(categories)}
+		return nil
+	}
+
+	var record linkJSON
+	if err := json.Unmarshal(data, &record); err != nil {
+		return err
+	}
+	*l = Link{
+		Categories: record.Categories,
+		Title:      record.Title,
+		Note:       record.Note,
+		OpenCount:  record.OpenCount,
+	}
+	for _, t := range []struct {
+		dst *time.Time
+		src *time.Time
+	}{{&l.Created, record.Created}, {&l.Updated, record.Updated}, {&l.LastOpened, record.LastOpened}} {
+		if t.src != nil {
+			*t.dst = *t.src
+		}
+	}
+	return nil
+}
+
+func timeOrNil(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// LinkRecords is the flat-file format of the links file:
+//
+//	url|categories|{"title":"...","created":"..."}
+//
+// The JSON column holds everything but the categories and is left out when
+// empty, so version 1 lines ("url|categories") are valid records.
+var LinkRecords linkFormat
+
+type linkFormat struct{}
+
+func (linkFormat) Header() string {
+	return fmt.Sprintf("# browsir links v%d", LinkVersion)
+}
+
+func (linkFormat) ParseLine(line string) (string, Link, bool) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return "", Link{}, false
+	}
+	parts := strings.SplitN(line, "|", 3)
+	if len(parts) < 2 {
+		return "", Link{}, false
+	}
+
+	var link Link
+	if len(parts) == 3 {
+		if err := json.Unmarshal([]byte(parts[2]), &link); err != nil {
+			return "", Link{}, false
+		}
+	}
+	link.Categories = SplitList(parts[1])
+	return strings.TrimSpace(parts[0]), link, true
+}
+
+func (linkFormat) FormatLine(url string, link Link) string {
+	line := url + "|" + strings.Join(link.Categories, ",")
+
+	link.Categories = nil
+	if data, err := json.Marshal(link); err == nil && string(data) != "{}" {
+		line += "|" + string(data)
+	}
+	return line
+}
+
+// SplitList splits a comma separated list, dropping the empty items.
+func SplitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,51 @@
+package storage
+
+// MemoryStore keeps entries in memory only. It is meant for tests.
+type MemoryStore[V any] struct {
+	entries map[string]V
+}
+
+// NewMemoryStore returns a store holding a copy of entries.
+func NewMemoryStore[V any](entries map[string]V) *MemoryStore[V] {
+	s := &MemoryStore[V]{entries: make(map[string]V, len(entries))}
+	for k, v := range entries {
+		s.entries[k] = v
+	}
+	return s
+}
+
+func (s *MemoryStore[V]) Load() error {
+	return nil
+}
+
+func (s *MemoryStore[V]) Get(key string) (V, bool) {
+	value, ok := s.entries[key]
+	return value, ok
+}
+
+func (s *MemoryStore[V]) Put(key string, value V) error {
+	s.entries[key] = value
+	return nil
+}
+
+func (s *MemoryStore[V]) Delete(keys ...string) error {
+	removed := 0
+	for _, k := range keys {
+		if _, ok := s.entries[k]; ok {
+			delete(s.entries, k)
+			removed++
+		}
+	}
+	if removed == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *MemoryStore[V]) List() map[string]V {
+	entries := make(map[string]V, len(s.entries))
+	for k, v := range s.entries {
+		entries[k] = v
+	}
+	return entries
+}
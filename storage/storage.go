@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"errors"
+	"sort"
+	"strings"
+)
+
+// ErrNotFound is returned when none of the requested keys are in a store.
+var ErrNotFound = errors.New("not found")
+
+// Store is a keyed collection of entries, such as local shortcuts (name to
+// url) or links (url to categories).
+type Store[V any] interface {
+	// Load (re)reads the entries from the backing medium.
+	Load() error
+	// Get returns the entry stored under key.
+	Get(key string) (V, bool)
+	// Put adds or replaces the entry stored under key.
+	Put(key string, value V) error
+	// Delete removes the given keys, returning ErrNotFound if none existed.
+	Delete(keys ...string) error
+	// List returns a copy of every entry.
+	List() map[string]V
+}
+
+// Format converts entries to and from the lines of a flat file.
+type Format[V any] interface {
+	ParseLine(line string) (key string, value V, ok bool)
+	FormatLine(key string, value V) string
+}
+
+// VersionedFormat is a Format whose files start with a header line naming
+// their version. Files lacking the current header are read all the same, and
+// rewritten in the current format by the first write.
+type VersionedFormat[V any] interface {
+	Format[V]
+	Header() string
+}
+
+// SeparatorFormat is the flat-file format used by the shortcuts ("name=url")
+// and links ("url|categories") files.
+type SeparatorFormat string
+
+const (
+	ShortcutFormat SeparatorFormat = "="
+	LinkFormat     SeparatorFormat = "|" // version 1 of the links file, see LinkRecords
+)
+
+func (f SeparatorFormat) ParseLine(line string) (string, string, bool) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return "", "", false
+	}
+	parts := strings.SplitN(line, string(f), 2)
+	if len(parts) != 2 {
+		return "", "", false
+	}
+	return strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1]), true
+}
+
+func (f SeparatorFormat) FormatLine(key, value string) string {
+	return key + string(f) + value
+}
+
+// Keys returns the keys of entries in sorted order.
+func Keys[V any](entries map[string]V) []string {
+	keys := make([]string, 0, len(entries))
+	for k := range entries {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
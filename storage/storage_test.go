@@ -0,0 +1,243 @@
+package storage
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStores(t *testing.T) {
+	dir := t.TempDir()
+
+	stores := map[string]Store[string]{
+		"file":   NewFileStore(filepath.Join(dir, "links"), LinkFormat),
+		"json":   NewJSONStore[string](filepath.Join(dir, "links.json")),
+		"memory": NewMemoryStore[string](nil),
+	}
+
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			if err := store.Load(); err != nil {
+				t.Fatalf("Error loading empty store: %v", err)
+			}
+			if err := store.Put("https://go.dev", "go"); err != nil {
+				t.Fatalf("Error putting entry: %v", err)
+			}
+			if err := store.Put("https://github.com", "code"); err != nil {
+				t.Fatalf("Error putting entry: %v", err)
+			}
+			if err := store.Put("https://go.dev", "go,docs"); err != nil {
+				t.Fatalf("Error replacing entry: %v", err)
+			}
+
+			if err := store.Load(); err != nil {
+				t.Fatalf("Error reloading store: %v", err)
+			}
+			if got, _ := store.Get("https://go.dev"); got != "go,docs" {
+				t.Errorf("got %v, want %v", got, "go,docs")
+			}
+			if got := len(store.List()); got != 2 {
+				t.Errorf("got %v entries, want %v", got, 2)
+			}
+
+			if err := store.Delete("https://github.com"); err != nil {
+				t.Fatalf("Error deleting entry: %v", err)
+			}
+			if err := store.Delete("https://github.com"); err != ErrNotFound {
+				t.Errorf("got %v, want %v", err, ErrNotFound)
+			}
+			if _, ok := store.Get("https://github.com"); ok {
+				t.Errorf("deleted entry still present")
+			}
+		})
+	}
+}
+
+func TestUnreadableStores(t *testing.T) {
+	dir := t.TempDir()
+	corrupt := `{"version":1,"entries":{"https://go.dev":"go",}}`
+	if err := os.WriteFile(filepath.Join(dir, "links.json"), []byte(corrupt), 0644); err != nil {
+		t.Fatalf("Error writing links file: %v", err)
+	}
+	longLine := "https://go.dev|" + strings.Repeat("go,", maxLineSize/3) + "\n"
+	if err := os.WriteFile(filepath.Join(dir, "links"), []byte(longLine), 0644); err != nil {
+		t.Fatalf("Error writing links file: %v", err)
+	}
+
+	stores := map[string]Store[string]{
+		"file": NewFileStore(filepath.Join(dir, "links"), LinkFormat),
+		"json": NewJSONStore[string](filepath.Join(dir, "links.json")),
+	}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			path := store.(interface{ Path() string }).Path()
+			before, _ := os.ReadFile(path)
+
+			if err := store.Load(); err == nil {
+				t.Fatalf("got no error loading %s", path)
+			}
+			if err := store.Put("https://github.com", "code"); err == nil {
+				t.Errorf("got no error putting an entry after a failed load")
+			}
+			if err := store.Delete("https://go.dev"); err == nil || err == ErrNotFound {
+				t.Errorf("got %v deleting an entry after a failed load", err)
+			}
+			if after, _ := os.ReadFile(path); string(after) != string(before) {
+				t.Errorf("the unreadable file was rewritten")
+			}
+		})
+	}
+}
+
+func TestLayeredStore(t *testing.T) {
+	dir := t.TempDir()
+	top := NewFileStore(filepath.Join(dir, "shortcuts"), ShortcutFormat)
+	if err := top.Put("gh", "github.com/me"); err != nil {
+		t.Fatalf("Error putting entry: %v", err)
+	}
+
+	base := NewMemoryStore(map[string]string{"gh": "github.com", "wiki": "wiki.example.com"})
+	var warnings strings.Builder
+	store := NewLayeredStore[string](base, top)
+	store.warn = &warnings
+	if err := store.Load(); err != nil {
+		t.Fatalf("Error loading store: %v", err)
+	}
+	if got := store.List(); !reflect.DeepEqual(got, map[string]string{"gh": "github.com/me", "wiki": "wiki.example.com"}) {
+		t.Errorf("got %v, want the top entry to win", got)
+	}
+
+	if err := store.Delete("wiki"); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("got %v deleting a base entry, want %v", err, ErrReadOnly)
+	}
+	if err := store.Delete("gh"); err != nil {
+		t.Fatalf("Error deleting entry: %v", err)
+	}
+	if got, _ := store.Get("gh"); got != "github.com" {
+		t.Errorf("got %v, want the base entry once the top one is deleted", got)
+	}
+
+	// A base directory in place of a file can not be read
+	if err := top.Put("gh", "github.com/me"); err != nil {
+		t.Fatalf("Error putting entry: %v", err)
+	}
+	store = NewLayeredStore[string](NewFileStore(dir, ShortcutFormat), top)
+	store.warn = &warnings
+	if err := store.Load(); err != nil {
+		t.Fatalf("got error %v, want the failing base skipped", err)
+	}
+	if got := store.List(); !reflect.DeepEqual(got, map[string]string{"gh": "github.com/me"}) {
+		t.Errorf("got %v, want the top entries", got)
+	}
+	if !strings.Contains(warnings.String(), "Warning: skipping the system-wide entries") {
+		t.Errorf("got warnings %q", warnings.String())
+	}
+}
+
+func TestFileStorePreservesComments(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "shortcuts")
+	if err := os.WriteFile(path, []byte("# team shortcuts\ngh=github.com\nmail=gmail.com"), 0644); err != nil {
+		t.Fatalf("Error writing shortcuts file: %v", err)
+	}
+
+	store := NewFileStore(path, ShortcutFormat)
+	if err := store.Put("cal", "calendar.google.com"); err != nil {
+		t.Fatalf("Error putting entry: %v", err)
+	}
+	if err := store.Delete("gh"); err != nil {
+		t.Fatalf("Error deleting entry: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Error reading shortcuts file: %v", err)
+	}
+	want := "# team shortcuts\nmail=gmail.com\ncal=calendar.google.com\n"
+	if string(data) != want {
+		t.Errorf("got %q, want %q", data, want)
+	}
+}
+
+func TestLinkRecords(t *testing.T) {
+	created := time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC)
+	link := Link{Categories: []string{"go", "docs"}, Title: "Go | Docs", Note: "start here", Created: created, OpenCount: 2}
+
+	line := LinkRecords.FormatLine("https://go.dev", link)
+	url, got, ok := LinkRecords.ParseLine(line)
+	if !ok || url != "https://go.dev" || !reflect.DeepEqual(got, link) {
+		t.Errorf("got %v %+v from %q, want %+v", url, got, line, link)
+	}
+
+	if line := LinkRecords.FormatLine("https://go.dev", Link{Categories: []string{"go"}}); line != "https://go.dev|go" {
+		t.Errorf("got %q, want a version 1 line", line)
+	}
+}
+
+func TestLinkMigration(t *testing.T) {
+	dir := t.TempDir()
+
+	t.Run("Test file", func(t *testing.T) {
+		path := filepath.Join(dir, "links")
+		if err := os.WriteFile(path, []byte("# my links\nhttps://go.dev|go,docs\n"), 0600); err != nil {
+			t.Fatalf("Error writing links file: %v", err)
+		}
+
+		store := NewFileStore(path, LinkRecords)
+		if err := store.Load(); err != nil {
+			t.Fatalf("Error loading links: %v", err)
+		}
+		if got, _ := store.Get("https://go.dev"); !reflect.DeepEqual(got.Categories, []string{"go", "docs"}) {
+			t.Errorf("got %v, want %v", got.Categories, []string{"go", "docs"})
+		}
+
+		// Read as is, so that files that can not be written are still read
+		if data, _ := os.ReadFile(path); string(data) != "# my links\nhttps://go.dev|go,docs\n" {
+			t.Errorf("got %q, want the file untouched by a load", data)
+		}
+
+		if err := store.Put("https://example.com", Link{Categories: []string{"example"}}); err != nil {
+			t.Fatalf("Error putting link: %v", err)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("Error reading links file: %v", err)
+		}
+		want := LinkRecords.Header() + "\n# my links\nhttps://go.dev|go,docs\nhttps://example.com|example\n"
+		if string(data) != want {
+			t.Errorf("got %q, want %q", data, want)
+		}
+		if info, _ := os.Stat(path); info.Mode().Perm() != 0600 {
+			t.Errorf("got mode %v, want %v", info.Mode().Perm(), os.FileMode(0600))
+		}
+	})
+
+	t.Run("Test json", func(t *testing.T) {
+		path := filepath.Join(dir, "links.json")
+		if err := os.WriteFile(path, []byte(`{"version":1,"entries":{"https://go.dev":"go,docs"}}`), 0644); err != nil {
+			t.Fatalf("Error writing links file: %v", err)
+		}
+
+		store := NewVersionedJSONStore[Link](path, LinkVersion)
+		if err := store.Load(); err != nil {
+			t.Fatalf("Error loading links: %v", err)
+		}
+		if got, _ := store.Get("https://go.dev"); !reflect.DeepEqual(got.Categories, []string{"go", "docs"}) {
+			t.Errorf("got %v, want %v", got.Categories, []string{"go", "docs"})
+		}
+
+		if err := store.Put("https://example.com", Link{Categories: []string{"example"}}); err != nil {
+			t.Fatalf("Error putting link: %v", err)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("Error reading links file: %v", err)
+		}
+		if !strings.Contains(string(data), `"version": 2`) || !strings.Contains(string(data), `"categories": [`) {
+			t.Errorf("got %s, want a version 2 document", data)
+		}
+	})
+}
@@ -0,0 +1,268 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/404answernotfound/browsir/storage"
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Bookmark formats read by ReadBookmarks.
+const (
+	NetscapeBookmarks = "html"     // bookmarks.html exported by any browser
+	ChromiumBookmarks = "chromium" // the Bookmarks file of a Chromium profile
+	FirefoxBookmarks  = "firefox"  // a copy of places.sqlite
+)
+
+// Bookmark is a link read from a browser.
+type Bookmark struct {
+	URL     string
+	Title   string
+	Folders []string // the folders holding it, outermost first
+	Added   time.Time
+}
+
+// DetectBookmarkFormat guesses the format of a bookmarks file from its
+// first bytes.
+func DetectBookmarkFormat(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(f, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return "", err
+	}
+	head = head[:n]
+
+	trimmed := bytes.ToLower(bytes.TrimSpace(head))
+	switch {
+	case bytes.HasPrefix(head, []byte("SQLite format 3\x00")):
+		return FirefoxBookmarks, nil
+	case bytes.HasPrefix(trimmed, []byte("{")):
+		return ChromiumBookmarks, nil
+	case bytes.Contains(trimmed, []byte("netscape-bookmark-file")), bytes.Contains(trimmed, []byte("<dl")):
+		return NetscapeBookmarks, nil
+	}
+	return "", fmt.Errorf("unknown bookmarks format, expected bookmarks.html, a Chromium Bookmarks file or places.sqlite")
+}
+
+// ReadBookmarks reads a bookmarks file in the given format, detecting it
+// when empty.
+func ReadBookmarks(path, format string) ([]Bookmark, error) {
+	if format == "" {
+		var err error
+		if format, err = DetectBookmarkFormat(path); err != nil {
+			return nil, err
+		}
+	}
+
+	if format == FirefoxBookmarks {
+		return readFirefoxBookmarks(path)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	switch format {
+	case NetscapeBookmarks:
+		return ReadNetscapeBookmarks(f)
+	case ChromiumBookmarks:
+		return ReadChromiumBookmarks(f)
+	}
+	return nil, fmt.Errorf("unknown bookmarks format: %s", format)
+}
+
+// ReadNetscapeBookmarks reads the bookmarks.html format, where folders are
+// <H3> headings followed by the <DL> list of their content. The toolbar and
+// "other bookmarks" folders of the browsers are not kept as folders.
+func ReadNetscapeBookmarks(r io.Reader) ([]Bookmark, error) {
+	doc, err := goquery.NewDocumentFromReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing bookmarks: %v", err)
+	}
+
+	var bookmarks []Bookmark
+	doc.Find("a[href]").Each(func(i int, a *goquery.Selection) {
+		href, _ := a.Attr("href")
+		bookmark := Bookmark{URL: strings.TrimSpace(href), Title: strings.TrimSpace(a.Text())}
+		if added, err := strconv.ParseInt(a.AttrOr("add_date", ""), 10, 64); err == nil && added > 0 {
+			bookmark.Added = time.Unix(added, 0)
+		}
+
+		// Parents go from the innermost list to the outermost one
+		a.ParentsFiltered("dl").Each(func(j int, dl *goquery.Selection) {
+			folder := dl.PrevAllFiltered("h3").First()
+			if folder.Length() == 0 || isRootFolder(folder) {
+				return
+			}
+			bookmark.Folders = append([]string{strings.TrimSpace(folder.Text())}, bookmark.Folders...)
+		})
+		bookmarks = append(bookmarks, bookmark)
+	})
+	return bookmarks, nil
+}
+
+func isRootFolder(h3 *goquery.Selection) bool {
+	for _, attr := range []string{"personal_toolbar_folder", "unfiled_bookmarks_folder"} {
+		if _, ok := h3.Attr(attr); ok {
+			return true
+		}
+	}
+	return false
+}
+
+type chromiumNode struct {
+	Type      string         `json:"type"`
+	Name      string         `json:"name"`
+	URL       string         `json:"url"`
+	DateAdded string         `json:"date_added"`
+	Children  []chromiumNode `json:"children"`
+}
+
+// chromiumEpoch is the Unix time, in microseconds, of 1601-01-01 where the
+// Chromium timestamps start.
+const chromiumEpoch = -11644473600 * 1000 * 1000
+
+// ReadChromiumBookmarks reads the Bookmarks file of a Chromium profile. The
+// bookmark bar, other and mobile roots are not kept as folders.
+func ReadChromiumBookmarks(r io.Reader) ([]Bookmark, error) {
+	var file struct {
+		Roots map[string]json.RawMessage `json:"roots"`
+	}
+	if err := json.NewDecoder(r).Decode(&file); err != nil {
+		return nil, fmt.Errorf("error parsing bookmarks: %v", err)
+	}
+	if file.Roots == nil {
+		return nil, fmt.Errorf("error parsing bookmarks: no roots")
+	}
+
+	var bookmarks []Bookmark
+	var walk func(node chromiumNode, folders []string)
+	walk = func(node chromiumNode, folders []string) {
+		if node.Type == "url" {
+			bookmark := Bookmark{URL: node.URL, Title: node.Name, Folders: folders}
+			if micros, err := strconv.ParseInt(node.DateAdded, 10, 64); err == nil && micros > 0 {
+				bookmark.Added = time.UnixMicro(chromiumEpoch + micros)
+			}
+			bookmarks = append(bookmarks, bookmark)
+			return
+		}
+		for _, child := range node.Children {
+			sub := folders
+			if child.Type == "folder" {
+				sub = append(folders[:len(folders):len(folders)], child.Name)
+			}
+			walk(child, sub)
+		}
+	}
+
+	for _, name := range storage.Keys(file.Roots) {
+		var root chromiumNode
+		// Not every root is a folder, e.g. "sync_transaction_version"
+		if err := json.Unmarshal(file.Roots[name], &root); err != nil || root.Type != "folder" {
+			continue
+		}
+		walk(root, nil)
+	}
+	return bookmarks, nil
+}
+
+// firefoxQuery lists the bookmarks and folders of places.sqlite. Type 1 is
+// a bookmark and 2 a folder.
+const firefoxQuery = `SELECT b.id, b.parent, b.type, IFNULL(b.guid, '') AS guid,
+	IFNULL(b.title, '') AS title, IFNULL(p.url, '') AS url, IFNULL(b.dateAdded, 0) AS added
+	FROM moz_bookmarks b LEFT JOIN moz_places p ON b.fk = p.id ORDER BY b.parent, b.position`
+
+type firefoxRow struct {
+	ID     int64  `json:"id"`
+	Parent int64  `json:"parent"`
+	Type   int    `json:"type"`
+	GUID   string `json:"guid"`
+	Title  string `json:"title"`
+	URL    string `json:"url"`
+	Added  int64  `json:"added"`
+}
+
+// readFirefoxBookmarks reads a places.sqlite database with the sqlite3
+// command. The menu, toolbar and unfiled roots are not kept as folders, and
+// the tags of a bookmark are added to its folders.
+func readFirefoxBookmarks(path string) ([]Bookmark, error) {
+	out, err := exec.Command("sqlite3", "-readonly", "-json", path, firefoxQuery).Output()
+	if errors.Is(err, exec.ErrNotFound) {
+		return nil, fmt.Errorf("reading places.sqlite needs the sqlite3 command")
+	}
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return nil, fmt.Errorf("error reading %s: %s", path, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return nil, fmt.Errorf("error reading %s: %v", path, err)
+	}
+
+	var rows []firefoxRow
+	if len(bytes.TrimSpace(out)) > 0 {
+		if err := json.Unmarshal(out, &rows); err != nil {
+			return nil, fmt.Errorf("error parsing sqlite3 output: %v", err)
+		}
+	}
+
+	nodes := make(map[int64]firefoxRow, len(rows))
+	for _, row := range rows {
+		nodes[row.ID] = row
+	}
+
+	// folders returns the path of a folder below the roots, and whether it
+	// is in the tags root.
+	var folders func(id int64) ([]string, bool)
+	folders = func(id int64) ([]string, bool) {
+		node, ok := nodes[id]
+		if !ok || node.Parent == 0 {
+			return nil, false
+		}
+		parent, ok := nodes[node.Parent]
+		if !ok || parent.Parent == 0 {
+			// A root: menu, toolbar, unfiled, mobile or tags
+			return nil, node.GUID == "tags________"
+		}
+		path, tag := folders(node.Parent)
+		return append(path, node.Title), tag
+	}
+
+	var bookmarks []Bookmark
+	tags := make(map[string][]string)
+	for _, row := range rows {
+		if row.Type != 1 || row.URL == "" {
+			continue
+		}
+		path, tag := folders(row.Parent)
+		if tag {
+			tags[row.URL] = append(tags[row.URL], path...)
+			continue
+		}
+		bookmark := Bookmark{URL: row.URL, Title: row.Title, Folders: path}
+		if row.Added > 0 {
+			bookmark.Added = time.UnixMicro(row.Added)
+		}
+		bookmarks = append(bookmarks, bookmark)
+	}
+	for i, bookmark := range bookmarks {
+		bookmarks[i].Folders = append(bookmark.Folders, tags[bookmark.URL]...)
+	}
+	return bookmarks, nil
+}
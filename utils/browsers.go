@@ -0,0 +1,189 @@
+package utils
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/404answernotfound/browsir/config"
+)
+
+// Argument families understood by OpenBrowser.
+const (
+	ChromiumFamily = "chromium"
+	GeckoFamily    = "gecko"
+	CustomFamily   = "custom"
+)
+
+// familyDefaults holds the flags used by a family when a browser definition
+// does not set its own.
+var familyDefaults = map[string]config.Browser{
+	ChromiumFamily: {
+		ProfileFlag:    "--profile-directory={profile}",
+		IncognitoFlag:  "--incognito",
+		NewWindowFlag:  "--new-window",
+		AppFlag:        "--app={url}",
+		KioskFlag:      "--kiosk",
+		WindowSizeFlag: "--window-size={width},{height}",
+	},
+	GeckoFamily: {
+		ProfileFlag:   "-profile {profile}",
+		IncognitoFlag: "-private-window",
+		NewWindowFlag: "-new-window",
+		KioskFlag:     "-kiosk",
+	},
+}
+
+var customBrowsers map[string]config.Browser
+
+// UseBrowsers sets the browser definitions from the config, which override or
+// extend the built-in ones.
+func UseBrowsers(browsers map[string]config.Browser) {
+	customBrowsers = browsers
+}
+
+// DefaultBrowsers returns the built-in browser definitions for the current OS.
+func DefaultBrowsers() map[string]config.Browser {
+	chromium := func(candidates ...string) config.Browser {
+		return config.Browser{Candidates: candidates, Family: ChromiumFamily}
+	}
+	gecko := func(candidates ...string) config.Browser {
+		return config.Browser{Candidates: candidates, Family: GeckoFamily}
+	}
+
+	switch runtime.GOOS {
+	case "darwin":
+		return map[string]config.Browser{
+			"chrome":                    chromium("/Applications/Google Chrome.app/Contents/MacOS/Google Chrome"),
+			"chromium":                  chromium("/Applications/Chromium.app/Contents/MacOS/Chromium"),
+			"brave":                     chromium("/Applications/Brave Browser.app/Contents/MacOS/Brave Browser"),
+			"arc":                       chromium("/Applications/Arc.app/Contents/MacOS/Arc"),
+			"vivaldi":                   chromium("/Applications/Vivaldi.app/Contents/MacOS/Vivaldi"),
+			"zen":                       gecko("/Applications/Zen.app/Contents/MacOS/zen", "/Applications/Zen Browser.app/Contents/MacOS/zen"),
+			"firefox":                   gecko("/Applications/Firefox.app/Contents/MacOS/firefox"),
+			"firefox-developer-edition": gecko("/Applications/Firefox Developer Edition.app/Contents/MacOS/firefox"),
+		}
+	case "linux":
+		return map[string]config.Browser{
+			"chrome":                    chromium(linuxCandidates("com.google.Chrome", "google-chrome", "google-chrome-stable")...),
+			"chromium":                  chromium(linuxCandidates("org.chromium.Chromium", "chromium", "chromium-browser")...),
+			"brave":                     chromium(linuxCandidates("com.brave.Browser", "brave-browser", "brave")...),
+			"vivaldi":                   chromium(linuxCandidates("com.vivaldi.Vivaldi", "vivaldi", "vivaldi-stable")...),
+			"zen":                       gecko(linuxCandidates("app.zen_browser.zen", "zen", "zen-browser")...),
+			"firefox":                   gecko(linuxCandidates("org.mozilla.firefox", "firefox")...),
+			"firefox-developer-edition": gecko(linuxCandidates("", "firefox-developer-edition")...),
+		}
+	case "windows":
+		programFiles, localAppData := os.Getenv("ProgramFiles"), os.Getenv("LOCALAPPDATA")
+		return map[string]config.Browser{
+			"chrome": chromium(
+				filepath.Join(programFiles, "Google", "Chrome", "Application", "chrome.exe"),
+				filepath.Join(localAppData, "Google", "Chrome", "Application", "chrome.exe"),
+			),
+			"brave": chromium(
+				filepath.Join(programFiles, "BraveSoftware", "Brave-Browser", "Application", "brave.exe"),
+				filepath.Join(localAppData, "BraveSoftware", "Brave-Browser", "Application", "brave.exe"),
+			),
+			"vivaldi": chromium(filepath.Join(localAppData, "Vivaldi", "Application", "vivaldi.exe")),
+			"arc":     chromium(filepath.Join(localAppData, "Microsoft", "WindowsApps", "Arc.exe")),
+			"firefox": gecko(filepath.Join(programFiles, "Mozilla Firefox", "firefox.exe")),
+			"zen":     gecko(filepath.Join(programFiles, "Zen Browser", "zen.exe")),
+		}
+	}
+	return map[string]config.Browser{}
+}
+
+// linuxCandidates lists the executables a browser may be installed as: the
+// given commands on the PATH, then snap and flatpak installs.
+func linuxCandidates(flatpakID string, commands ...string) []string {
+	candidates := append([]string{}, commands...)
+	for _, command := range commands {
+		candidates = append(candidates, filepath.Join("/snap/bin", command))
+	}
+	if flatpakID != "" {
+		candidates = append(candidates,
+			filepath.Join(os.Getenv("HOME"), ".local/share/flatpak/exports/bin", flatpakID),
+			filepath.Join("/var/lib/flatpak/exports/bin", flatpakID),
+		)
+	}
+	return candidates
+}
+
+// LookupBrowser returns the definition of a browser: the built-in one, if
+// any, overlaid with the one from the config. Flags that are still unset are
+// taken from the browser's argument family.
+func LookupBrowser(browserName string) (config.Browser, bool) {
+	browser, builtin := DefaultBrowsers()[browserName]
+	custom, configured := customBrowsers[browserName]
+	if !builtin && !configured {
+		return config.Browser{}, false
+	}
+
+	if custom.Path != "" {
+		browser.Path = custom.Path
+	}
+	if len(custom.Candidates) > 0 {
+		browser.Candidates = custom.Candidates
+	}
+	if custom.Family != "" {
+		browser.Family = custom.Family
+	}
+	if browser.Family == "" {
+		browser.Family = ChromiumFamily
+	}
+
+	defaults := familyDefaults[browser.Family]
+	browser.ProfileFlag = firstNonEmpty(custom.ProfileFlag, defaults.ProfileFlag)
+	browser.IncognitoFlag = firstNonEmpty(custom.IncognitoFlag, defaults.IncognitoFlag)
+	browser.NewWindowFlag = firstNonEmpty(custom.NewWindowFlag, defaults.NewWindowFlag)
+	browser.AppFlag = firstNonEmpty(custom.AppFlag, defaults.AppFlag)
+	browser.KioskFlag = firstNonEmpty(custom.KioskFlag, defaults.KioskFlag)
+	browser.WindowSizeFlag = firstNonEmpty(custom.WindowSizeFlag, defaults.WindowSizeFlag)
+
+	return browser, true
+}
+
+// executable picks the browser executable: the explicit path, else the first
+// candidate that is installed, else the first candidate.
+func executable(browser config.Browser) string {
+	if browser.Path != "" {
+		return browser.Path
+	}
+	for _, candidate := range browser.Candidates {
+		if filepath.IsAbs(candidate) {
+			if _, err := os.Stat(candidate); err == nil {
+				return candidate
+			}
+		} else if path, err := exec.LookPath(candidate); err == nil {
+			return path
+		}
+	}
+	if len(browser.Candidates) > 0 {
+		return browser.Candidates[0]
+	}
+	return ""
+}
+
+// expandFlag turns a flag template such as "-profile {profile}" into
+// arguments, replacing the placeholders with values.
+func expandFlag(template string, values map[string]string) []string {
+	var args []string
+	for _, field := range strings.Fields(template) {
+		for name, value := range values {
+			field = strings.ReplaceAll(field, "{"+name+"}", value)
+		}
+		args = append(args, field)
+	}
+	return args
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
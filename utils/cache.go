@@ -0,0 +1,152 @@
+package utils
+
+import (
+	"errors"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/404answernotfound/browsir/config"
+	"github.com/404answernotfound/browsir/storage"
+)
+
+// CachedPreview is a page preview kept by a PreviewCache, with what is
+// needed to revalidate it.
+type CachedPreview struct {
+	Preview
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"last_modified,omitempty"`
+	Fetched      time.Time `json:"fetched"`
+}
+
+// PreviewCache keeps page previews on disk, keyed by normalized URL, so that
+// titles are known offline and pages are not downloaded on every preview.
+type PreviewCache struct {
+	store *storage.JSONStore[CachedPreview]
+	ttl   time.Duration
+	now   func() time.Time
+}
+
+// CacheStats describes the content of a PreviewCache.
+type CacheStats struct {
+	Path    string `json:"path" yaml:"path"`
+	Entries int    `json:"entries" yaml:"entries"`
+	Fresh   int    `json:"fresh" yaml:"fresh"` // younger than the TTL
+	Stale   int    `json:"stale" yaml:"stale"`
+	TTL     string `json:"ttl" yaml:"ttl"`
+	Size    int64  `json:"size" yaml:"size"` // in bytes
+}
+
+// NewPreviewCache returns a cache kept in path, whose entries are used
+// without asking the site for ttl.
+func NewPreviewCache(path string, ttl time.Duration) *PreviewCache {
+	return &PreviewCache{store: storage.NewJSONStore[CachedPreview](path), ttl: ttl, now: time.Now}
+}
+
+// OpenPreviewCache returns the cache of the user, in the XDG cache directory.
+func OpenPreviewCache(ttl time.Duration) *PreviewCache {
+	return NewPreviewCache(config.ResolvePaths().CacheFile("previews.json"), ttl)
+}
+
+// Lookup returns the cached preview of a link, however old it is.
+func (c *PreviewCache) Lookup(link string) (CachedPreview, bool) {
+	return c.store.Get(NormalizeURL(link))
+}
+
+// Fetch returns the preview of a link, from the cache while it is fresh.
+// Stale entries are revalidated with their ETag and Last-Modified headers,
+// and used as they are when the site can not be reached. With refresh the
+// page is always downloaded again.
+func (c *PreviewCache) Fetch(link string, timeout time.Duration, refresh bool) (Preview, error) {
+	key := NormalizeURL(link)
+	cached, ok := c.store.Get(key)
+	if ok && !refresh && c.now().Sub(cached.Fetched) < c.ttl {
+		return cached.Preview, nil
+	}
+
+	var sent validators
+	if ok && !refresh {
+		sent = validators{ETag: cached.ETag, LastModified: cached.LastModified}
+	}
+	preview, received, err := fetchPreview(link, timeout, sent)
+	switch {
+	case errors.Is(err, errNotModified):
+		preview = cached.Preview
+	case err != nil && ok && !refresh:
+		return cached.Preview, nil
+	case err != nil:
+		return Preview{}, err
+	}
+
+	// Best effort, a preview is still good when the cache can not be written
+	_ = c.store.Put(key, CachedPreview{
+		Preview:      preview,
+		ETag:         received.ETag,
+		LastModified: received.LastModified,
+		Fetched:      c.now(),
+	})
+	return preview, nil
+}
+
+// FillTitles sets the title of the links that have none to their cached
+// one, without going online.
+func (c *PreviewCache) FillTitles(links map[string]storage.Link) {
+	for link, record := range links {
+		if record.Title != "" {
+			continue
+		}
+		if cached, ok := c.Lookup(link); ok && cached.Title != "" {
+			record.Title = cached.Title
+			links[link] = record
+		}
+	}
+}
+
+// Stats counts the entries of the cache.
+func (c *PreviewCache) Stats() CacheStats {
+	stats := CacheStats{Path: c.store.Path(), TTL: c.ttl.String()}
+	for _, cached := range c.store.List() {
+		stats.Entries++
+		if c.now().Sub(cached.Fetched) < c.ttl {
+			stats.Fresh++
+		}
+	}
+	stats.Stale = stats.Entries - stats.Fresh
+	if info, err := os.Stat(c.store.Path()); err == nil {
+		stats.Size = info.Size()
+	}
+	return stats
+}
+
+// Clear removes every entry of the cache, returning how many there were.
+func (c *PreviewCache) Clear() (int, error) {
+	entries := len(c.store.List())
+	if err := os.Remove(c.store.Path()); err != nil && !os.IsNotExist(err) {
+		return 0, err
+	}
+	return entries, c.store.Load()
+}
+
+// NormalizeURL turns the spellings of a URL into one: https is assumed when
+// there is no scheme, the scheme and host are lowercased, and default ports,
+// fragments and trailing slashes are dropped.
+func NormalizeURL(link string) string {
+	if !strings.Contains(link, "://") {
+		link = "https://" + link
+	}
+	u, err := url.Parse(link)
+	if err != nil {
+		return link
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	if port := u.Port(); (u.Scheme == "https" && port == "443") || (u.Scheme == "http" && port == "80") {
+		u.Host = strings.TrimSuffix(u.Host, ":"+port)
+	}
+	u.Fragment = ""
+	u.RawFragment = ""
+	u.Path = strings.TrimSuffix(u.Path, "/")
+	u.RawPath = strings.TrimSuffix(u.RawPath, "/")
+	return u.String()
+}
@@ -0,0 +1,144 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// userAgent is sent by the link checker, some sites refusing Go's default.
+const userAgent = "Mozilla/5.0 (compatible; browsir)"
+
+// LinkStatus is the outcome of checking a link.
+type LinkStatus struct {
+	URL      string
+	Status   int    // HTTP status of the final response, 0 when there is none
+	FinalURL string // where the redirects led, empty without redirects
+	Moved    bool   // only permanent redirects led to FinalURL
+	Latency  time.Duration
+	Err      error
+}
+
+// Dead reports whether the link is gone: its host does not exist, or it was
+// answered with 404 or 410.
+func (s LinkStatus) Dead() bool {
+	var dnsErr *net.DNSError
+	if errors.As(s.Err, &dnsErr) {
+		return dnsErr.IsNotFound
+	}
+	return s.Status == http.StatusNotFound || s.Status == http.StatusGone
+}
+
+// Failed reports whether the check was inconclusive: a timeout, a refused
+// connection or an error status such as 403 or 503, all of which may well be
+// temporary.
+func (s LinkStatus) Failed() bool {
+	return !s.Dead() && (s.Err != nil || s.Status >= 400)
+}
+
+// CheckOptions tune CheckLinks.
+type CheckOptions struct {
+	Workers  int           // concurrent requests, 8 when not set
+	Timeout  time.Duration // per link, HEAD and GET included
+	Client   *http.Client  // http.DefaultClient when nil
+	OnResult func(LinkStatus)
+}
+
+// CheckLinks probes urls concurrently and returns their status in the same
+// order. OnResult, if set, is called as each link is checked.
+func CheckLinks(urls []string, opts CheckOptions) []LinkStatus {
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = 8
+	}
+	client := opts.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	results := make([]LinkStatus, len(urls))
+	jobs := make(chan int)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for w := 0; w < workers && w < len(urls); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = CheckLink(client, urls[i], opts.Timeout)
+				if opts.OnResult != nil {
+					mu.Lock()
+					opts.OnResult(results[i])
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	for i := range urls {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return results
+}
+
+// CheckLink probes a link with HEAD, falling back to GET for the servers
+// that do not answer HEAD properly, and follows its redirects.
+func CheckLink(client *http.Client, url string, timeout time.Duration) LinkStatus {
+	status := LinkStatus{URL: url}
+	target := url
+	if !strings.Contains(target, "://") {
+		target = "https://" + target
+	}
+
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	resp, err := probe(ctx, client, http.MethodHead, target)
+	if err != nil || resp.StatusCode >= 400 {
+		if resp != nil {
+			resp.Body.Close()
+		}
+		resp, err = probe(ctx, client, http.MethodGet, target)
+	}
+	status.Latency = time.Since(start)
+	if err != nil {
+		status.Err = err
+		return status
+	}
+	defer resp.Body.Close()
+	// Drain a little so the connection can be reused
+	io.CopyN(io.Discard, resp.Body, 4096)
+
+	status.Status = resp.StatusCode
+	if final := resp.Request.URL.String(); final != target {
+		status.FinalURL = final
+		status.Moved = true
+		// resp.Request.Response is the redirect that led to the request
+		for r := resp.Request.Response; r != nil; r = r.Request.Response {
+			if r.StatusCode != http.StatusMovedPermanently && r.StatusCode != http.StatusPermanentRedirect {
+				status.Moved = false
+			}
+		}
+	}
+	return status
+}
+
+func probe(ctx context.Context, client *http.Client, method, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	return client.Do(req)
+}
@@ -0,0 +1,146 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// ClipboardProvider reads and writes the text of the clipboard.
+type ClipboardProvider interface {
+	ReadClipboard() (string, error)
+	WriteClipboard(text string) error
+}
+
+// FakeClipboard is an in-memory clipboard, e.g. for tests.
+type FakeClipboard struct {
+	Text string
+}
+
+func (c *FakeClipboard) ReadClipboard() (string, error) {
+	return c.Text, nil
+}
+
+func (c *FakeClipboard) WriteClipboard(text string) error {
+	c.Text = text
+	return nil
+}
+
+// systemClipboard runs the clipboard tools of the desktop.
+type systemClipboard struct{}
+
+// clipboardCommands are tried in order, the Wayland ones only in a Wayland
+// session. They read the clipboard, or write it with write.
+func clipboardCommands(write bool) [][]string {
+	switch runtime.GOOS {
+	case "darwin":
+		if write {
+			return [][]string{{"pbcopy"}}
+		}
+		return [][]string{{"pbpaste"}}
+	case "windows":
+		if write {
+			return [][]string{{"clip"}}
+		}
+		return [][]string{{"powershell", "-NoProfile", "-Command", "Get-Clipboard"}}
+	}
+
+	var commands [][]string
+	if write {
+		if os.Getenv("WAYLAND_DISPLAY") != "" {
+			commands = append(commands, []string{"wl-copy"})
+		}
+		return append(commands,
+			[]string{"xclip", "-selection", "clipboard", "-in"},
+			[]string{"xsel", "--clipboard", "--input"},
+		)
+	}
+	if os.Getenv("WAYLAND_DISPLAY") != "" {
+		commands = append(commands, []string{"wl-paste", "--no-newline"})
+	}
+	return append(commands,
+		[]string{"xclip", "-selection", "clipboard", "-out"},
+		[]string{"xsel", "--clipboard", "--output"},
+	)
+}
+
+func (systemClipboard) ReadClipboard() (string, error) {
+	return runClipboard(clipboardCommands(false), "")
+}
+
+func (systemClipboard) WriteClipboard(text string) error {
+	_, err := runClipboard(clipboardCommands(true), text)
+	return err
+}
+
+// runClipboard runs the first installed command, giving it input, and
+// returns its output.
+func runClipboard(commands [][]string, input string) (string, error) {
+	for _, command := range commands {
+		path, err := exec.LookPath(command[0])
+		if err != nil {
+			continue
+		}
+		cmd := exec.Command(path, command[1:]...)
+		cmd.Stdin = strings.NewReader(input)
+		out, err := cmd.Output()
+		if err != nil {
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+				return "", fmt.Errorf("error using the clipboard with %s: %s", command[0], strings.TrimSpace(string(exitErr.Stderr)))
+			}
+			return "", fmt.Errorf("error using the clipboard with %s: %v", command[0], err)
+		}
+		return string(out), nil
+	}
+	return "", fmt.Errorf("using the clipboard needs wl-clipboard, xclip or xsel")
+}
+
+var clipboard ClipboardProvider = systemClipboard{}
+
+// UseClipboard replaces the clipboard read by ReadClipboard, e.g. with a
+// FakeClipboard in tests. nil restores the system clipboard.
+func UseClipboard(provider ClipboardProvider) {
+	if provider == nil {
+		provider = systemClipboard{}
+	}
+	clipboard = provider
+}
+
+// ReadClipboard returns the text of the clipboard.
+func ReadClipboard() (string, error) {
+	return clipboard.ReadClipboard()
+}
+
+// WriteClipboard copies text to the clipboard.
+func WriteClipboard(text string) error {
+	return clipboard.WriteClipboard(text)
+}
+
+// ExtractURLs returns the http and https URLs of a text, in order and without
+// duplicates. Everything else, such as comments or punctuation around the
+// URLs, is dropped.
+func ExtractURLs(text string) []string {
+	var urls []string
+	seen := make(map[string]bool)
+	for _, field := range strings.Fields(text) {
+		field = strings.TrimLeft(field, `"'<([{`)
+		field = strings.TrimRight(field, `"'>)]},;.!?:`)
+		if !strings.HasPrefix(field, "http://") && !strings.HasPrefix(field, "https://") {
+			continue
+		}
+		u, err := url.Parse(field)
+		if err != nil || u.Host == "" {
+			continue
+		}
+		if key := NormalizeURL(field); !seen[key] {
+			seen[key] = true
+			urls = append(urls, field)
+		}
+	}
+	return urls
+}
@@ -0,0 +1,258 @@
+package utils
+
+import (
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/404answernotfound/browsir/storage"
+)
+
+// Export formats of ExportLinks.
+const (
+	ExportHTML     = "html" // Netscape bookmarks, read by every browser
+	ExportMarkdown = "md"
+	ExportCSV      = "csv"
+	ExportOPML     = "opml"
+	ExportJSON     = "json"
+)
+
+// ExportFormats lists the formats accepted by ExportLinks.
+var ExportFormats = []string{ExportHTML, ExportMarkdown, ExportCSV, ExportOPML, ExportJSON}
+
+// ExportLinks writes links to w in one of the ExportFormats. Links are
+// grouped by the given categories, or by all of theirs when none are given.
+func ExportLinks(w io.Writer, format string, links map[string]storage.Link, categories []string) error {
+	switch format {
+	case ExportHTML:
+		return exportHTML(w, links, categories)
+	case ExportMarkdown:
+		return exportMarkdown(w, links, categories)
+	case ExportCSV:
+		return exportCSV(w, links)
+	case ExportOPML:
+		return exportOPML(w, links, categories)
+	case ExportJSON:
+		return WriteListing(w, OutputJSON, LinkListing(links))
+	}
+	return fmt.Errorf("unknown export format: %s, use %s", format, strings.Join(ExportFormats, ", "))
+}
+
+type linkGroup struct {
+	category string
+	urls     []string
+}
+
+// groupLinks returns the links of every category, sorted by name, and the
+// links in none of them. With once, a link is only in its first category.
+func groupLinks(links map[string]storage.Link, categories []string, once bool) ([]linkGroup, []string) {
+	if len(categories) == 0 {
+		all := make(map[string]string)
+		for _, record := range links {
+			for _, c := range record.Categories {
+				all[c] = c
+			}
+		}
+		categories = storage.Keys(all)
+	}
+
+	index := make(map[string]int, len(categories))
+	groups := make([]linkGroup, len(categories))
+	for i, c := range categories {
+		index[c] = i
+		groups[i].category = c
+	}
+
+	var rest []string
+	for _, url := range storage.Keys(links) {
+		grouped := false
+		for _, c := range links[url].Categories {
+			i, ok := index[c]
+			if !ok || (once && grouped) {
+				continue
+			}
+			groups[i].urls = append(groups[i].urls, url)
+			grouped = true
+		}
+		if !grouped {
+			rest = append(rest, url)
+		}
+	}
+
+	nonEmpty := groups[:0]
+	for _, g := range groups {
+		if len(g.urls) > 0 {
+			nonEmpty = append(nonEmpty, g)
+		}
+	}
+	return nonEmpty, rest
+}
+
+func linkTitle(url string, record storage.Link) string {
+	if record.Title != "" {
+		return record.Title
+	}
+	return url
+}
+
+// exportHTML writes the Netscape bookmark format, a link being in the folder
+// of its first category and tagged with all of them.
+func exportHTML(w io.Writer, links map[string]storage.Link, categories []string) error {
+	var b strings.Builder
+	b.WriteString(`<!DOCTYPE NETSCAPE-Bookmark-file-1>
+<!-- This is an automatically generated file.
+     It will be read and overwritten.
+     DO NOT EDIT! -->
+<META HTTP-EQUIV="Content-Type" CONTENT="text/html; charset=UTF-8">
+<TITLE>Bookmarks</TITLE>
+<H1>Bookmarks</H1>
+<DL><p>
+`)
+
+	writeLink := func(indent, url string) {
+		record := links[url]
+		fmt.Fprintf(&b, "%s<DT><A HREF=\"%s\"", indent, html.EscapeString(url))
+		if !record.Created.IsZero() {
+			fmt.Fprintf(&b, " ADD_DATE=\"%d\"", record.Created.Unix())
+		}
+		if !record.Updated.IsZero() {
+			fmt.Fprintf(&b, " LAST_MODIFIED=\"%d\"", record.Updated.Unix())
+		}
+		if len(record.Categories) > 0 {
+			fmt.Fprintf(&b, " TAGS=\"%s\"", html.EscapeString(strings.Join(record.Categories, ",")))
+		}
+		fmt.Fprintf(&b, ">%s</A>\n", html.EscapeString(linkTitle(url, record)))
+		if record.Note != "" {
+			fmt.Fprintf(&b, "%s<DD>%s\n", indent, html.EscapeString(record.Note))
+		}
+	}
+
+	groups, rest := groupLinks(links, categories, true)
+	for _, g := range groups {
+		fmt.Fprintf(&b, "    <DT><H3>%s</H3>\n    <DL><p>\n", html.EscapeString(g.category))
+		for _, url := range g.urls {
+			writeLink("        ", url)
+		}
+		b.WriteString("    </DL><p>\n")
+	}
+	for _, url := range rest {
+		writeLink("    ", url)
+	}
+	b.WriteString("</DL><p>\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// exportMarkdown writes a section per category, links being listed in every
+// category they have.
+func exportMarkdown(w io.Writer, links map[string]storage.Link, categories []string) error {
+	var b strings.Builder
+	b.WriteString("# Links\n")
+
+	writeLinks := func(urls []string) {
+		b.WriteString("\n")
+		for _, url := range urls {
+			record := links[url]
+			title := strings.NewReplacer("[", `\[`, "]", `\]`).Replace(linkTitle(url, record))
+			fmt.Fprintf(&b, "- [%s](%s)", title, url)
+			if record.Note != "" {
+				fmt.Fprintf(&b, " - %s", record.Note)
+			}
+			b.WriteString("\n")
+		}
+	}
+
+	groups, rest := groupLinks(links, categories, false)
+	for _, g := range groups {
+		fmt.Fprintf(&b, "\n## %s\n", g.category)
+		writeLinks(g.urls)
+	}
+	if len(rest) > 0 && len(categories) == 0 {
+		b.WriteString("\n## Uncategorized\n")
+		writeLinks(rest)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func exportCSV(w io.Writer, links map[string]storage.Link) error {
+	formatTime := func(t time.Time) string {
+		if t.IsZero() {
+			return ""
+		}
+		return t.Format(time.RFC3339)
+	}
+
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"url", "title", "categories", "note", "created", "updated", "last_opened", "open_count"})
+	for _, url := range storage.Keys(links) {
+		record := links[url]
+		writer.Write([]string{
+			url,
+			record.Title,
+			strings.Join(record.Categories, ","),
+			record.Note,
+			formatTime(record.Created),
+			formatTime(record.Updated),
+			formatTime(record.LastOpened),
+			strconv.Itoa(record.OpenCount),
+		})
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+type opmlOutline struct {
+	Text     string        `xml:"text,attr"`
+	Type     string        `xml:"type,attr,omitempty"`
+	URL      string        `xml:"url,attr,omitempty"`
+	Outlines []opmlOutline `xml:"outline"`
+}
+
+type opmlDocument struct {
+	XMLName  xml.Name      `xml:"opml"`
+	Version  string        `xml:"version,attr"`
+	Title    string        `xml:"head>title"`
+	Outlines []opmlOutline `xml:"body>outline"`
+}
+
+// exportOPML writes an outline per category, holding its links.
+func exportOPML(w io.Writer, links map[string]storage.Link, categories []string) error {
+	doc := opmlDocument{Version: "2.0", Title: "browsir links"}
+
+	outline := func(url string) opmlOutline {
+		return opmlOutline{Text: linkTitle(url, links[url]), Type: "link", URL: url}
+	}
+
+	groups, rest := groupLinks(links, categories, false)
+	for _, g := range groups {
+		folder := opmlOutline{Text: g.category}
+		for _, url := range g.urls {
+			folder.Outlines = append(folder.Outlines, outline(url))
+		}
+		doc.Outlines = append(doc.Outlines, folder)
+	}
+	if len(categories) == 0 {
+		for _, url := range rest {
+			doc.Outlines = append(doc.Outlines, outline(url))
+		}
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,56 @@
+package utils
+
+import (
+	"sort"
+	"time"
+
+	"github.com/404answernotfound/browsir/config"
+	"github.com/404answernotfound/browsir/storage"
+)
+
+// maxHistory bounds the URLs kept by the history of opened URLs.
+const maxHistory = 100
+
+var history storage.Store[time.Time]
+
+// SetHistory replaces the store of recently opened URLs, e.g. with an
+// in-memory store in tests.
+func SetHistory(store storage.Store[time.Time]) {
+	history = store
+}
+
+func historyStore() storage.Store[time.Time] {
+	if history == nil {
+		history = storage.NewJSONStore[time.Time](config.ResolvePaths().CacheFile("history.json"))
+	}
+	return history
+}
+
+// recordHistory remembers when urls were opened, forgetting the oldest URLs
+// beyond maxHistory. It is best effort like recordOpened.
+func recordHistory(urls []string, now time.Time) {
+	store := historyStore()
+	for _, url := range urls {
+		if err := store.Put(url, now); err != nil {
+			return
+		}
+	}
+	if old := recentFirst(store.List()); len(old) > maxHistory {
+		_ = store.Delete(old[maxHistory:]...)
+	}
+}
+
+// RecentURLs returns up to n of the URLs opened last, most recent first.
+func RecentURLs(n int) []string {
+	recent := recentFirst(historyStore().List())
+	if len(recent) > n {
+		recent = recent[:n]
+	}
+	return recent
+}
+
+func recentFirst(opened map[string]time.Time) []string {
+	urls := storage.Keys(opened)
+	sort.SliceStable(urls, func(i, j int) bool { return opened[urls[i]].After(opened[urls[j]]) })
+	return urls
+}
@@ -0,0 +1,51 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// ErrNothingPicked is returned by RunLauncher when the launcher was closed
+// without picking anything.
+var ErrNothingPicked = errors.New("nothing picked")
+
+// RunLauncher runs a launcher such as rofi or dmenu, given as a command line
+// with a {prompt} placeholder, with the entries on its stdin, and returns the
+// line it printed: one of the entries, or the free text typed instead. With
+// fzf --print-query, the query comes first and the entry last, so the last
+// line wins.
+func RunLauncher(command string, prompt string, entries []string) (string, error) {
+	args := expandFlag(command, map[string]string{"prompt": prompt})
+	if len(args) == 0 {
+		return "", fmt.Errorf("empty launcher command")
+	}
+	path, err := exec.LookPath(args[0])
+	if err != nil {
+		return "", fmt.Errorf("launcher %s not found: %v", args[0], err)
+	}
+
+	cmd := exec.Command(path, args[1:]...)
+	cmd.Stdin = strings.NewReader(strings.Join(entries, "\n") + "\n")
+	cmd.Stderr = os.Stderr
+	out, runErr := cmd.Output()
+
+	choice := ""
+	for _, line := range strings.Split(string(out), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			choice = line
+		}
+	}
+	// Launchers exit with an error when closed, and fzf also when the query
+	// matches nothing, printing the query all the same
+	if choice == "" {
+		var exitErr *exec.ExitError
+		if runErr == nil || errors.As(runErr, &exitErr) {
+			return "", ErrNothingPicked
+		}
+		return "", fmt.Errorf("error running %s: %v", args[0], runErr)
+	}
+	return choice, nil
+}
@@ -0,0 +1,326 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/404answernotfound/browsir/config"
+	"github.com/404answernotfound/browsir/storage"
+	"gopkg.in/yaml.v3"
+)
+
+// Output formats of the listing commands, chosen with --output.
+const (
+	OutputJSON  = "json"
+	OutputYAML  = "yaml"
+	OutputTSV   = "tsv"
+	OutputTable = "table"
+)
+
+// OutputFormats lists the formats accepted by WriteListing.
+var OutputFormats = []string{OutputJSON, OutputYAML, OutputTSV, OutputTable}
+
+// Where an entry comes from.
+const (
+	SourceConfig  = "config"
+	SourceLocal   = "local"
+	SourceBrowser = "browser" // found by profiles discover
+)
+
+// Listing is the output of a listing command. It is encoded as is in JSON
+// and YAML, and as Header and Rows in TSV and tables.
+type Listing interface {
+	Header() []string
+	Rows() [][]string
+}
+
+type ProfileEntry struct {
+	Name        string `json:"name" yaml:"name"`
+	ProfileDir  string `json:"profile_dir" yaml:"profile_dir"`
+	Description string `json:"description" yaml:"description"`
+	Source      string `json:"source" yaml:"source"`
+}
+
+type ShortcutEntry struct {
+	Name   string `json:"name" yaml:"name"`
+	URL    string `json:"url" yaml:"url"`
+	Source string `json:"source" yaml:"source"`
+}
+
+type LinkEntry struct {
+	URL        string     `json:"url" yaml:"url"`
+	Title      string     `json:"title,omitempty" yaml:"title,omitempty"`
+	Categories []string   `json:"categories" yaml:"categories"`
+	Note       string     `json:"note,omitempty" yaml:"note,omitempty"`
+	Created    *time.Time `json:"created,omitempty" yaml:"created,omitempty"`
+	Updated    *time.Time `json:"updated,omitempty" yaml:"updated,omitempty"`
+	LastOpened *time.Time `json:"last_opened,omitempty" yaml:"last_opened,omitempty"`
+	OpenCount  int        `json:"open_count" yaml:"open_count"`
+	Source     string     `json:"source" yaml:"source"`
+}
+
+type SessionEntry struct {
+	Name    string   `json:"name" yaml:"name"`
+	Profile string   `json:"profile,omitempty" yaml:"profile,omitempty"`
+	Mode    string   `json:"mode" yaml:"mode"`
+	Delay   string   `json:"delay,omitempty" yaml:"delay,omitempty"`
+	URLs    []string `json:"urls" yaml:"urls"`
+}
+
+// Profiles lists the profiles, in the order of the config file.
+type Profiles []ProfileEntry
+
+// Shortcuts lists shortcuts sorted by name, the config one first when a
+// local shortcut has the same name.
+type Shortcuts []ShortcutEntry
+
+// Links lists links sorted by URL.
+type Links []LinkEntry
+
+// Sessions lists sessions sorted by name.
+type Sessions []SessionEntry
+
+// Usage is what PrintUsage shows: the profiles and every shortcut.
+type Usage struct {
+	Profiles  Profiles  `json:"profiles" yaml:"profiles"`
+	Shortcuts Shortcuts `json:"shortcuts" yaml:"shortcuts"`
+}
+
+func ProfileListing(profiles []config.Profile) Profiles {
+	entries := make(Profiles, 0, len(profiles))
+	for _, p := range profiles {
+		entries = append(entries, ProfileEntry{Name: p.Name, ProfileDir: p.ProfileDir, Description: p.Description, Source: SourceConfig})
+	}
+	return entries
+}
+
+// DiscoveredListing lists the profiles found in a browser, as they would be
+// added to the config.
+func DiscoveredListing(discovered []DiscoveredProfile) Profiles {
+	entries := make(Profiles, 0, len(discovered))
+	for _, d := range discovered {
+		p := d.Profile()
+		entries = append(entries, ProfileEntry{Name: p.Name, ProfileDir: p.ProfileDir, Description: p.Description, Source: SourceBrowser})
+	}
+	return entries
+}
+
+func ShortcutListing(shortcuts, localShortcuts map[string]string) Shortcuts {
+	entries := make(Shortcuts, 0, len(shortcuts)+len(localShortcuts))
+	for _, name := range storage.Keys(shortcuts) {
+		entries = append(entries, ShortcutEntry{Name: name, URL: shortcuts[name], Source: SourceConfig})
+	}
+	for _, name := range storage.Keys(localShortcuts) {
+		entries = append(entries, ShortcutEntry{Name: name, URL: localShortcuts[name], Source: SourceLocal})
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+	return entries
+}
+
+func LinkListing(links map[string]storage.Link) Links {
+	entries := make(Links, 0, len(links))
+	for _, link := range storage.Keys(links) {
+		entries = append(entries, linkEntry(link, links[link]))
+	}
+	return entries
+}
+
+func SessionListing(sessions map[string]config.Session) Sessions {
+	entries := make(Sessions, 0, len(sessions))
+	for _, name := range storage.Keys(sessions) {
+		session := sessions[name]
+		entry := SessionEntry{Name: name, Profile: session.Profile, Mode: session.Mode, URLs: session.URLs}
+		if entry.Mode == "" {
+			entry.Mode = config.SessionWindow
+		}
+		if session.Delay > 0 {
+			entry.Delay = session.Delay.String()
+		}
+		if entry.URLs == nil {
+			entry.URLs = []string{}
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// MatchListing lists the links found by SearchLinks, best first.
+func MatchListing(matches []LinkMatch) Links {
+	entries := make(Links, 0, len(matches))
+	for _, m := range matches {
+		entries = append(entries, linkEntry(m.URL, m.Link))
+	}
+	return entries
+}
+
+func linkEntry(link string, record storage.Link) LinkEntry {
+	entry := LinkEntry{
+		URL:        link,
+		Title:      record.Title,
+		Categories: record.Categories,
+		Note:       record.Note,
+		OpenCount:  record.OpenCount,
+		Source:     SourceLocal,
+	}
+	if entry.Categories == nil {
+		entry.Categories = []string{}
+	}
+	for _, t := range []struct {
+		dst **time.Time
+		src time.Time
+	}{{&entry.Created, record.Created}, {&entry.Updated, record.Updated}, {&entry.LastOpened, record.LastOpened}} {
+		if !t.src.IsZero() {
+			src := t.src
+			*t.dst = &src
+		}
+	}
+	return entry
+}
+
+func (p Profiles) Header() []string { return []string{"NAME", "PROFILE_DIR", "DESCRIPTION", "SOURCE"} }
+
+func (p Profiles) Rows() [][]string {
+	rows := make([][]string, 0, len(p))
+	for _, e := range p {
+		rows = append(rows, []string{e.Name, e.ProfileDir, e.Description, e.Source})
+	}
+	return rows
+}
+
+func (s Shortcuts) Header() []string { return []string{"NAME", "URL", "SOURCE"} }
+
+func (s Shortcuts) Rows() [][]string {
+	rows := make([][]string, 0, len(s))
+	for _, e := range s {
+		rows = append(rows, []string{e.Name, e.URL, e.Source})
+	}
+	return rows
+}
+
+func (l Links) Header() []string {
+	return []string{"URL", "TITLE", "CATEGORIES", "OPENED", "SOURCE"}
+}
+
+func (l Links) Rows() [][]string {
+	rows := make([][]string, 0, len(l))
+	for _, e := range l {
+		rows = append(rows, []string{e.URL, e.Title, strings.Join(e.Categories, ","), strconv.Itoa(e.OpenCount), e.Source})
+	}
+	return rows
+}
+
+func (s Sessions) Header() []string { return []string{"NAME", "PROFILE", "MODE", "DELAY", "URLS"} }
+
+func (s Sessions) Rows() [][]string {
+	rows := make([][]string, 0, len(s))
+	for _, e := range s {
+		rows = append(rows, []string{e.Name, e.Profile, e.Mode, e.Delay, strings.Join(e.URLs, ",")})
+	}
+	return rows
+}
+
+// In TSV and tables the profiles and shortcuts share the columns, the first
+// one telling them apart.
+func (u Usage) Header() []string { return []string{"KIND", "NAME", "VALUE", "SOURCE"} }
+
+func (u Usage) Rows() [][]string {
+	var rows [][]string
+	for _, e := range u.Profiles {
+		rows = append(rows, []string{"profile", e.Name, e.ProfileDir, e.Source})
+	}
+	for _, e := range u.Shortcuts {
+		rows = append(rows, []string{"shortcut", e.Name, e.URL, e.Source})
+	}
+	return rows
+}
+
+// WriteListing writes listing to w in one of the OutputFormats.
+func WriteListing(w io.Writer, format string, listing Listing) error {
+	switch format {
+	case OutputJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(listing)
+	case OutputYAML:
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(listing); err != nil {
+			return err
+		}
+		return encoder.Close()
+	case OutputTSV:
+		for _, row := range cells(listing) {
+			if _, err := fmt.Fprintln(w, strings.Join(row, "\t")); err != nil {
+				return err
+			}
+		}
+		return nil
+	case OutputTable:
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		for _, row := range cells(listing) {
+			fmt.Fprintln(tw, strings.Join(row, "\t"))
+		}
+		return tw.Flush()
+	}
+	return fmt.Errorf("unknown output format: %s, use %s", format, strings.Join(OutputFormats, ", "))
+}
+
+// cells returns the header and the rows of listing, without the tabs and
+// newlines that would break the columns.
+func cells(listing Listing) [][]string {
+	replacer := strings.NewReplacer("\t", " ", "\n", " ")
+	rows := append([][]string{listing.Header()}, listing.Rows()...)
+	for _, row := range rows {
+		for i, field := range row {
+			row[i] = replacer.Replace(field)
+		}
+	}
+	return rows
+}
+
+func (s CacheStats) Header() []string {
+	return []string{"PATH", "ENTRIES", "FRESH", "STALE", "TTL", "SIZE"}
+}
+
+func (s CacheStats) Rows() [][]string {
+	return [][]string{{s.Path, strconv.Itoa(s.Entries), strconv.Itoa(s.Fresh), strconv.Itoa(s.Stale), s.TTL, strconv.FormatInt(s.Size, 10)}}
+}
+
+// A preview lists its fields one per row, the H1 headings and the OpenGraph
+// and Twitter card fields each on their own.
+func (p Preview) Header() []string { return []string{"FIELD", "VALUE"} }
+
+func (p Preview) Rows() [][]string {
+	var rows [][]string
+	field := func(label, value string) {
+		if value != "" {
+			rows = append(rows, []string{label, value})
+		}
+	}
+	field("URL", p.URL)
+	field("Title", p.Title)
+	field("Description", p.Description)
+	for _, h1 := range p.H1 {
+		field("H1", h1)
+	}
+	field("Canonical", p.Canonical)
+	field("Favicon", p.Favicon)
+	field("Language", p.Lang)
+	field("Published", p.Published)
+	if p.Words > 0 {
+		field("Reading time", fmt.Sprintf("%d min (%d words)", p.ReadingTime, p.Words))
+	}
+	for _, key := range storage.Keys(p.OpenGraph) {
+		field(key, p.OpenGraph[key])
+	}
+	for _, key := range storage.Keys(p.Twitter) {
+		field(key, p.Twitter[key])
+	}
+	return rows
+}
@@ -0,0 +1,90 @@
+package utils
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/404answernotfound/browsir/storage"
+)
+
+// Kinds of PickItem.
+const (
+	PickRecent   = "recent"
+	PickShortcut = "shortcut"
+	PickLink     = "link"
+)
+
+// PickItem is an entry of the picker: a shortcut, a link of the library or
+// a recently opened URL.
+type PickItem struct {
+	Kind   string
+	Name   string // the shortcut, or the URL
+	URL    string
+	Detail string // categories and title of a link, "local" for local shortcuts
+}
+
+// PickItems lists the recent URLs, most recent first, then the shortcuts,
+// local ones replacing those of the config, then the other links.
+func PickItems(shortcuts, localShortcuts map[string]string, links map[string]storage.Link, recent []string) []PickItem {
+	linkDetail := func(url string) string {
+		record := links[url]
+		detail := strings.Join(record.Categories, ",")
+		if record.Title != "" {
+			detail = strings.TrimSpace(detail + " " + record.Title)
+		}
+		return detail
+	}
+
+	var items []PickItem
+	listed := make(map[string]bool)
+	for _, url := range recent {
+		items = append(items, PickItem{Kind: PickRecent, Name: url, URL: url, Detail: linkDetail(url)})
+		listed[url] = true
+	}
+
+	names := make(map[string]bool)
+	for name := range shortcuts {
+		names[name] = true
+	}
+	for name := range localShortcuts {
+		names[name] = true
+	}
+	for _, name := range storage.Keys(names) {
+		if url, ok := localShortcuts[name]; ok {
+			items = append(items, PickItem{Kind: PickShortcut, Name: name, URL: url, Detail: "local"})
+		} else {
+			items = append(items, PickItem{Kind: PickShortcut, Name: name, URL: shortcuts[name]})
+		}
+	}
+
+	for _, url := range storage.Keys(links) {
+		if !listed[url] {
+			items = append(items, PickItem{Kind: PickLink, Name: url, URL: url, Detail: linkDetail(url)})
+		}
+	}
+	return items
+}
+
+// FilterPickItems returns the indexes of the items matching query fuzzily,
+// best first, or of every item when query is empty.
+func FilterPickItems(items []PickItem, query string) []int {
+	terms := strings.Fields(query)
+	var indexes []int
+	scores := make(map[int]int)
+	for i, item := range items {
+		text := item.Name + " " + item.Detail
+		if item.Name != item.URL {
+			text += " " + item.URL
+		}
+		score, ok := matchTerms(text, terms, true)
+		if !ok {
+			continue
+		}
+		indexes = append(indexes, i)
+		scores[i] = score
+	}
+	if len(terms) > 0 {
+		sort.SliceStable(indexes, func(a, b int) bool { return scores[indexes[a]] > scores[indexes[b]] })
+	}
+	return indexes
+}
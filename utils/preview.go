@@ -0,0 +1,283 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/404answernotfound/browsir/storage"
+	"github.com/PuerkitoBio/goquery"
+)
+
+// maxPreviewSize bounds the part of a page read by FetchPreview.
+const maxPreviewSize = 5 << 20
+
+// wordsPerMinute is the reading speed behind Preview.ReadingTime.
+const wordsPerMinute = 200
+
+// Preview is what a page tells about itself.
+type Preview struct {
+	URL         string            `json:"url" yaml:"url"` // after redirects
+	Title       string            `json:"title" yaml:"title"`
+	Description string            `json:"description,omitempty" yaml:"description,omitempty"`
+	H1          []string          `json:"h1,omitempty" yaml:"h1,omitempty"`
+	Canonical   string            `json:"canonical,omitempty" yaml:"canonical,omitempty"`
+	Favicon     string            `json:"favicon,omitempty" yaml:"favicon,omitempty"`
+	Lang        string            `json:"lang,omitempty" yaml:"lang,omitempty"`
+	Published   string            `json:"published,omitempty" yaml:"published,omitempty"`
+	OpenGraph   map[string]string `json:"opengraph,omitempty" yaml:"opengraph,omitempty"` // og:* properties
+	Twitter     map[string]string `json:"twitter,omitempty" yaml:"twitter,omitempty"`     // twitter:* card fields
+	Words       int               `json:"words" yaml:"words"`
+	ReadingTime int               `json:"reading_time_minutes" yaml:"reading_time_minutes"`
+}
+
+// FetchPreview downloads a page and reads its title, description, headings,
+// OpenGraph and Twitter card fields, canonical link, favicon, language and
+// publication date, and estimates its reading time. URLs without a scheme
+// are fetched over https.
+func FetchPreview(link string, timeout time.Duration) (Preview, error) {
+	preview, _, err := fetchPreview(link, timeout, validators{})
+	return preview, err
+}
+
+// validators are the ETag and Last-Modified headers of a page, sent back to
+// only download it again when it changed.
+type validators struct {
+	ETag         string
+	LastModified string
+}
+
+// errNotModified is returned by fetchPreview when the page did not change
+// since the validators were given.
+var errNotModified = errors.New("not modified")
+
+func fetchPreview(link string, timeout time.Duration, cached validators) (Preview, validators, error) {
+	if !strings.Contains(link, "://") {
+		link = "https://" + link
+	}
+
+	reqCtx, cancel := context.WithDeadline(context.Background(), time.Now().Add(timeout))
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(reqCtx, "GET", link, nil)
+	if err != nil {
+		return Preview{}, validators{}, fmt.Errorf("error creating request: %s", err)
+	}
+	req.Header.Set("User-Agent", userAgent)
+	if cached.ETag != "" {
+		req.Header.Set("If-None-Match", cached.ETag)
+	}
+	if cached.LastModified != "" {
+		req.Header.Set("If-Modified-Since", cached.LastModified)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return Preview{}, validators{}, fmt.Errorf("error making request: %s", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotModified {
+		return Preview{}, cached, errNotModified
+	}
+	if resp.StatusCode >= 400 {
+		return Preview{}, validators{}, fmt.Errorf("error fetching %s: %s", link, resp.Status)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxPreviewSize))
+	if err != nil {
+		return Preview{}, validators{}, fmt.Errorf("error reading response body: %s", err)
+	}
+
+	body = decodeCharset(link, body, resp.Header.Get("Content-Type"))
+	preview, err := parsePreview(body, resp.Request.URL)
+	if err != nil {
+		return Preview{}, validators{}, err
+	}
+	return preview, validators{ETag: resp.Header.Get("ETag"), LastModified: resp.Header.Get("Last-Modified")}, nil
+}
+
+// parsePreview reads a page decoded to UTF-8, base resolving its relative
+// links.
+func parsePreview(body []byte, base *url.URL) (Preview, error) {
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		return Preview{}, fmt.Errorf("error parsing page: %s", err)
+	}
+
+	preview := Preview{
+		URL:       base.String(),
+		OpenGraph: make(map[string]string),
+		Twitter:   make(map[string]string),
+	}
+
+	doc.Find("meta").Each(func(i int, meta *goquery.Selection) {
+		// OpenGraph uses property, Twitter cards name, pages mix them up
+		key := strings.ToLower(meta.AttrOr("property", meta.AttrOr("name", "")))
+		content := strings.TrimSpace(meta.AttrOr("content", ""))
+		if content == "" {
+			return
+		}
+		switch {
+		case key == "description":
+			preview.Description = content
+		case strings.HasPrefix(key, "og:"):
+			preview.OpenGraph[key] = content
+		case strings.HasPrefix(key, "twitter:"):
+			preview.Twitter[key] = content
+		case key == "article:published_time" && preview.Published == "":
+			preview.Published = content
+		}
+	})
+
+	preview.Title = strings.TrimSpace(doc.Find("title").First().Text())
+	if preview.Title == "" {
+		preview.Title = preview.OpenGraph["og:title"]
+	}
+	if preview.Description == "" {
+		preview.Description = preview.OpenGraph["og:description"]
+	}
+
+	doc.Find("h1").Each(func(i int, h1 *goquery.Selection) {
+		if text := strings.Join(strings.Fields(h1.Text()), " "); text != "" {
+			preview.H1 = append(preview.H1, text)
+		}
+	})
+
+	preview.Lang = strings.TrimSpace(doc.Find("html").AttrOr("lang", ""))
+
+	if href, ok := doc.Find("link[rel~='canonical']").Attr("href"); ok {
+		preview.Canonical = resolveURL(base, href)
+	}
+
+	preview.Favicon = resolveURL(base, "/favicon.ico")
+	for _, selector := range []string{"link[rel~='icon']", "link[rel='apple-touch-icon']"} {
+		if href, ok := doc.Find(selector).Attr("href"); ok {
+			preview.Favicon = resolveURL(base, href)
+			break
+		}
+	}
+
+	doc.Find("script[type='application/ld+json']").EachWithBreak(func(i int, script *goquery.Selection) bool {
+		var data any
+		if json.Unmarshal([]byte(script.Text()), &data) != nil {
+			return true
+		}
+		if published := findJSONKey(data, "datePublished"); published != "" {
+			preview.Published = published
+			return false
+		}
+		return true
+	})
+
+	preview.Words = countWords(doc)
+	preview.ReadingTime = (preview.Words + wordsPerMinute - 1) / wordsPerMinute
+	return preview, nil
+}
+
+// countWords counts the words of the main text of a page: its article or
+// main element when it has one, without scripts and navigation.
+func countWords(doc *goquery.Document) int {
+	main := doc.Find("article").First()
+	if main.Length() == 0 {
+		main = doc.Find("main").First()
+	}
+	if main.Length() == 0 {
+		main = doc.Find("body")
+	}
+	main = main.Clone()
+	main.Find("script, style, noscript, template, nav, header, footer, aside").Remove()
+	return len(strings.Fields(main.Text()))
+}
+
+// findJSONKey returns the first string value of key in a JSON-LD document,
+// looking through nested objects, arrays and @graph.
+func findJSONKey(data any, key string) string {
+	switch v := data.(type) {
+	case map[string]any:
+		if s, ok := v[key].(string); ok && s != "" {
+			return s
+		}
+		for _, k := range storage.Keys(v) {
+			if s := findJSONKey(v[k], key); s != "" {
+				return s
+			}
+		}
+	case []any:
+		for _, child := range v {
+			if s := findJSONKey(child, key); s != "" {
+				return s
+			}
+		}
+	}
+	return ""
+}
+
+func resolveURL(base *url.URL, href string) string {
+	ref, err := url.Parse(strings.TrimSpace(href))
+	if err != nil {
+		return href
+	}
+	return base.ResolveReference(ref).String()
+}
+
+// metaCharset finds the charset declared by a <meta> tag.
+var metaCharset = regexp.MustCompile(`(?i)<meta[^>]+charset\s*=\s*["']?\s*([a-z0-9_:.-]+)`)
+
+// decodeCharset converts a page to UTF-8, reading its charset from the
+// Content-Type header or, failing that, from its <meta> tags. Only UTF-8 and
+// the Latin-1 family, which browsers all read as windows-1252, are known:
+// other pages are read as they are, with a warning.
+func decodeCharset(link string, body []byte, contentType string) []byte {
+	charset := ""
+	if _, params, err := mime.ParseMediaType(contentType); err == nil {
+		charset = params["charset"]
+	}
+	if charset == "" {
+		head := body
+		if len(head) > 1024 {
+			head = head[:1024]
+		}
+		if m := metaCharset.FindSubmatch(head); m != nil {
+			charset = string(m[1])
+		}
+	}
+
+	switch strings.ToLower(strings.TrimSpace(charset)) {
+	case "", "utf-8", "utf8":
+		return bytes.TrimPrefix(body, []byte("\xef\xbb\xbf"))
+	case "us-ascii", "ascii", "iso-8859-1", "iso8859-1", "latin1", "l1", "windows-1252", "cp1252", "x-cp1252":
+		return decodeWindows1252(body)
+	}
+	fmt.Fprintf(os.Stderr, "Warning: unsupported charset %s for %s, its text may be garbled\n", charset, link)
+	return body
+}
+
+// windows1252 holds the characters of 0x80 to 0x9f, where windows-1252
+// differs from Latin-1. The unassigned bytes map to themselves.
+var windows1252 = [32]rune{
+	'€', 0x81, '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', 0x8d, 'Ž', 0x8f,
+	0x90, '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', 0x9d, 'ž', 'Ÿ',
+}
+
+func decodeWindows1252(body []byte) []byte {
+	decoded := make([]byte, 0, len(body)+len(body)/8)
+	for _, b := range body {
+		r := rune(b)
+		if b >= 0x80 && b < 0xa0 {
+			r = windows1252[b-0x80]
+		}
+		decoded = utf8.AppendRune(decoded, r)
+	}
+	return decoded
+}
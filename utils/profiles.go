@@ -0,0 +1,186 @@
+package utils
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/404answernotfound/browsir/config"
+)
+
+// DiscoveredProfile is a browser profile found in the browser's own data.
+type DiscoveredProfile struct {
+	Browser string
+	Name    string // human-readable name, as shown by the browser
+	Dir     string // value for profile_dir in the config
+}
+
+// Profile converts the discovered profile into a config profile, deriving the
+// command name from the human-readable one.
+func (p DiscoveredProfile) Profile() config.Profile {
+	name := strings.ToLower(strings.Join(strings.Fields(p.Name), "-"))
+	return config.Profile{
+		Name:        name,
+		ProfileDir:  p.Dir,
+		Description: fmt.Sprintf("%s (%s)", p.Name, p.Browser),
+	}
+}
+
+// DiscoverProfiles lists the profiles of a browser, reading Chromium's
+// "Local State" file or Firefox's "profiles.ini".
+func DiscoverProfiles(browserName string) ([]DiscoveredProfile, error) {
+	dir, gecko, err := profileDataDir(browserName)
+	if err != nil {
+		return nil, err
+	}
+
+	var profiles []DiscoveredProfile
+	if gecko {
+		profiles, err = readProfilesIni(filepath.Join(dir, "profiles.ini"))
+	} else {
+		profiles, err = readLocalState(filepath.Join(dir, "Local State"))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range profiles {
+		profiles[i].Browser = browserName
+	}
+	sort.Slice(profiles, func(i, j int) bool {
+		return profiles[i].Dir < profiles[j].Dir
+	})
+	return profiles, nil
+}
+
+// profileDataDir returns the directory holding a browser's profiles and
+// whether the browser is Firefox based.
+func profileDataDir(browserName string) (string, bool, error) {
+	home := os.Getenv("HOME")
+
+	switch runtime.GOOS {
+	case "darwin":
+		support := filepath.Join(home, "Library", "Application Support")
+		switch browserName {
+		case "chrome":
+			return filepath.Join(support, "Google", "Chrome"), false, nil
+		case "brave":
+			return filepath.Join(support, "BraveSoftware", "Brave-Browser"), false, nil
+		case "vivaldi":
+			return filepath.Join(support, "Vivaldi"), false, nil
+		case "arc":
+			return filepath.Join(support, "Arc", "User Data"), false, nil
+		case "firefox", "firefox-developer-edition":
+			return filepath.Join(support, "Firefox"), true, nil
+		case "zen":
+			return filepath.Join(support, "zen"), true, nil
+		}
+	case "linux":
+		switch browserName {
+		case "chrome":
+			return filepath.Join(home, ".config", "google-chrome"), false, nil
+		case "chromium":
+			return filepath.Join(home, ".config", "chromium"), false, nil
+		case "brave":
+			return filepath.Join(home, ".config", "BraveSoftware", "Brave-Browser"), false, nil
+		case "vivaldi":
+			return filepath.Join(home, ".config", "vivaldi"), false, nil
+		case "firefox", "firefox-developer-edition":
+			return filepath.Join(home, ".mozilla", "firefox"), true, nil
+		case "zen":
+			return filepath.Join(home, ".zen"), true, nil
+		}
+	case "windows":
+		local, roaming := os.Getenv("LOCALAPPDATA"), os.Getenv("APPDATA")
+		switch browserName {
+		case "chrome":
+			return filepath.Join(local, "Google", "Chrome", "User Data"), false, nil
+		case "brave":
+			return filepath.Join(local, "BraveSoftware", "Brave-Browser", "User Data"), false, nil
+		case "vivaldi":
+			return filepath.Join(local, "Vivaldi", "User Data"), false, nil
+		case "firefox", "firefox-developer-edition":
+			return filepath.Join(roaming, "Mozilla", "Firefox"), true, nil
+		case "zen":
+			return filepath.Join(roaming, "zen"), true, nil
+		}
+	}
+	return "", false, fmt.Errorf("profile discovery is not supported for %s on %s", browserName, runtime.GOOS)
+}
+
+// readLocalState reads the profiles from Chromium's "Local State" JSON file,
+// whose profile.info_cache maps profile directories to their details.
+func readLocalState(path string) ([]DiscoveredProfile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var state struct {
+		Profile struct {
+			InfoCache map[string]struct {
+				Name string `json:"name"`
+			} `json:"info_cache"`
+		} `json:"profile"`
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("error parsing %s: %v", path, err)
+	}
+
+	var profiles []DiscoveredProfile
+	for dir, info := range state.Profile.InfoCache {
+		profiles = append(profiles, DiscoveredProfile{Name: info.Name, Dir: dir})
+	}
+	return profiles, nil
+}
+
+// readProfilesIni reads the profiles from Firefox's "profiles.ini" file. The
+// profile directories are returned as absolute paths, as expected by the
+// -profile flag.
+func readProfilesIni(path string) ([]DiscoveredProfile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var profiles []DiscoveredProfile
+	var current map[string]string
+	flush := func() {
+		if current == nil || current["Path"] == "" {
+			return
+		}
+		dir := filepath.FromSlash(current["Path"])
+		if current["IsRelative"] == "1" {
+			dir = filepath.Join(filepath.Dir(path), dir)
+		}
+		profiles = append(profiles, DiscoveredProfile{Name: current["Name"], Dir: dir})
+	}
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, ";") || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			flush()
+			current = nil
+			if strings.HasPrefix(line, "[Profile") {
+				current = make(map[string]string)
+			}
+			continue
+		}
+		if key, value, ok := strings.Cut(line, "="); ok && current != nil {
+			current[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+	flush()
+
+	return profiles, scanner.Err()
+}
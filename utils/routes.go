@@ -0,0 +1,72 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/404answernotfound/browsir/config"
+)
+
+// ErrNoRoute is returned when no route matches and there is no fallback.
+var ErrNoRoute = errors.New("no route matches")
+
+// RouteMatch tells which profile was picked for a URL and why.
+type RouteMatch struct {
+	Profile string
+	Route   int // index of the matching route, -1 for the fallback
+	Reason  string
+}
+
+// RouteURL picks the profile of a URL from the routes, falling back to the
+// given profile when none of them match.
+func RouteURL(routes []config.Route, fallback string, rawURL string) (RouteMatch, error) {
+	if !strings.Contains(rawURL, "://") {
+		rawURL = "https://" + rawURL
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return RouteMatch{}, fmt.Errorf("invalid url %s: %v", rawURL, err)
+	}
+	host := strings.ToLower(u.Hostname())
+
+	for i, route := range routes {
+		if pattern, ok := strings.CutPrefix(route.Match, "regex:"); ok {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return RouteMatch{}, fmt.Errorf("route %d: invalid regex %s: %v", i+1, pattern, err)
+			}
+			if re.MatchString(rawURL) {
+				return RouteMatch{
+					Profile: route.Profile,
+					Route:   i,
+					Reason:  fmt.Sprintf("url %s matches regex %s", rawURL, pattern),
+				}, nil
+			}
+			continue
+		}
+
+		// Patterns with a path are matched against the url without its
+		// scheme, the others against the host only
+		subject, what := host, "host"
+		if strings.Contains(route.Match, "://") {
+			subject, what = rawURL, "url"
+		} else if strings.Contains(route.Match, "/") {
+			subject, what = strings.TrimPrefix(rawURL, u.Scheme+"://"), "url"
+		}
+		if MatchGlob(route.Match, subject) {
+			return RouteMatch{
+				Profile: route.Profile,
+				Route:   i,
+				Reason:  fmt.Sprintf("%s %s matches %s", what, subject, route.Match),
+			}, nil
+		}
+	}
+
+	if fallback == "" {
+		return RouteMatch{}, fmt.Errorf("%w %s and no default_profile is set", ErrNoRoute, rawURL)
+	}
+	return RouteMatch{Profile: fallback, Route: -1, Reason: "no route matched, using default_profile"}, nil
+}
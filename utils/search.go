@@ -0,0 +1,139 @@
+package utils
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/404answernotfound/browsir/storage"
+)
+
+// LinkQuery filters the links searched by SearchLinks.
+type LinkQuery struct {
+	Terms       []string // every term has to match the URL, the categories, the title or the note
+	Categories  []string // every category has to be present, or one with AnyCategory
+	AnyCategory bool
+	Fuzzy       bool // terms match as subsequences, and rank the results
+}
+
+// LinkMatch is a link found by SearchLinks.
+type LinkMatch struct {
+	URL string
+	storage.Link
+	Score int
+}
+
+// SearchLinks returns the links matching query. Terms are matched case
+// insensitively as substrings, or as subsequences with Fuzzy. Fuzzy results
+// are ranked best first, the others are sorted by URL.
+func SearchLinks(links map[string]storage.Link, query LinkQuery) []LinkMatch {
+	var matches []LinkMatch
+	for _, link := range storage.Keys(links) {
+		record := links[link]
+		if !matchCategories(record.Categories, query.Categories, query.AnyCategory) {
+			continue
+		}
+
+		text := strings.Join([]string{link, strings.Join(record.Categories, " "), record.Title, record.Note}, " ")
+		score, ok := matchTerms(text, query.Terms, query.Fuzzy)
+		if !ok {
+			continue
+		}
+		matches = append(matches, LinkMatch{URL: link, Link: record, Score: score})
+	}
+
+	if query.Fuzzy {
+		sort.SliceStable(matches, func(i, j int) bool { return matches[i].Score > matches[j].Score })
+	}
+	return matches
+}
+
+func matchCategories(categories, wanted []string, any bool) bool {
+	if len(wanted) == 0 {
+		return true
+	}
+	for _, w := range wanted {
+		found := false
+		for _, c := range categories {
+			if strings.EqualFold(c, w) {
+				found = true
+				break
+			}
+		}
+		if found && any {
+			return true
+		}
+		if !found && !any {
+			return false
+		}
+	}
+	return !any
+}
+
+func matchTerms(text string, terms []string, fuzzy bool) (int, bool) {
+	total := 0
+	for _, term := range terms {
+		if !fuzzy {
+			if !strings.Contains(strings.ToLower(text), strings.ToLower(term)) {
+				return 0, false
+			}
+			continue
+		}
+		score, ok := FuzzyScore(term, text)
+		if !ok {
+			return 0, false
+		}
+		total += score
+	}
+	return total, true
+}
+
+// FuzzyScore reports whether the characters of pattern appear in text in
+// order, ignoring case, and how well: consecutive characters, characters
+// starting a word and short texts score higher.
+func FuzzyScore(pattern, text string) (int, bool) {
+	p := []rune(strings.ToLower(pattern))
+	t := []rune(strings.ToLower(text))
+	if len(p) == 0 {
+		return 0, true
+	}
+
+	// Matching greedily from every occurrence of the first character finds
+	// "doc" in "go.dev/doc" rather than the d of "dev".
+	best, found := 0, false
+	for start, r := range t {
+		if r != p[0] {
+			continue
+		}
+		if score, ok := fuzzyFrom(p, t, start); ok && (!found || score > best) {
+			best, found = score, true
+		}
+	}
+	if !found {
+		return 0, false
+	}
+
+	if strings.Contains(string(t), string(p)) {
+		best += 2 * len(p)
+	}
+	return best*10 - len(t)/10, true
+}
+
+func fuzzyFrom(p, t []rune, start int) (int, bool) {
+	score, next, previous := 0, 0, -2
+	for i := start; i < len(t) && next < len(p); i++ {
+		if t[i] != p[next] {
+			continue
+		}
+		score++
+		if i == previous+1 {
+			score += 5
+		}
+		if i == 0 || !unicode.IsLetter(t[i-1]) && !unicode.IsDigit(t[i-1]) {
+			score += 3
+		}
+		previous = i
+		next++
+	}
+	return score, next == len(p)
+}
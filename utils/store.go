@@ -0,0 +1,127 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/404answernotfound/browsir/config"
+	"github.com/404answernotfound/browsir/storage"
+)
+
+var (
+	shortcuts storage.Store[string]
+	links     storage.Store[storage.Link]
+)
+
+// UseStorage opens the shortcut and link stores selected by the storage
+// section of the config. Per-user shortcuts and links are layered over the
+// system-wide ones, so a machine-wide file can provide defaults that users
+// override, and they are only ever written per user.
+func UseStorage(cfg config.Storage) error {
+	shortcutsName, linksName, openShortcuts, openLinks, err := backend(cfg)
+	if err != nil {
+		return err
+	}
+	paths := config.ResolvePaths()
+
+	shortcutsPath := pathOr(cfg.Shortcuts, paths.UserDataFile(shortcutsName))
+	userShortcuts := openShortcuts(shortcutsPath)
+	if systemPath := paths.SystemDataFile(shortcutsName); systemPath != shortcutsPath {
+		userShortcuts = storage.NewLayeredStore(openShortcuts(systemPath), userShortcuts)
+	}
+
+	linksPath := pathOr(cfg.Links, paths.UserDataFile(linksName))
+	userLinks := openLinks(linksPath)
+	if systemPath := paths.SystemDataFile(linksName); systemPath != linksPath {
+		userLinks = storage.NewLayeredStore(openLinks(systemPath), userLinks)
+	}
+
+	SetStores(userShortcuts, userLinks)
+	return nil
+}
+
+// backend returns the file names of a storage backend and how to open them.
+func backend(cfg config.Storage) (shortcutsName, linksName string, openShortcuts func(path string) storage.Store[string], openLinks func(path string) storage.Store[storage.Link], err error) {
+	switch cfg.Backend {
+	case "", "file":
+		shortcutsName, linksName = "shortcuts", "links"
+		openShortcuts = func(path string) storage.Store[string] {
+			return storage.NewFileStore(path, storage.ShortcutFormat)
+		}
+		openLinks = func(path string) storage.Store[storage.Link] {
+			return storage.NewFileStore(path, storage.LinkRecords)
+		}
+	case "json":
+		shortcutsName, linksName = "shortcuts.json", "links.json"
+		openShortcuts = func(path string) storage.Store[string] {
+			return storage.NewJSONStore[string](path)
+		}
+		openLinks = func(path string) storage.Store[storage.Link] {
+			return storage.NewVersionedJSONStore[storage.Link](path, storage.LinkVersion)
+		}
+	default:
+		err = fmt.Errorf("unknown storage backend: %s", cfg.Backend)
+	}
+	return
+}
+
+// SetStores replaces the shortcut and link stores, e.g. with in-memory stores
+// in tests.
+func SetStores(shortcutStore storage.Store[string], linkStore storage.Store[storage.Link]) {
+	shortcuts = shortcutStore
+	links = linkStore
+}
+
+func shortcutStore() storage.Store[string] {
+	if shortcuts == nil {
+		_ = UseStorage(config.Storage{})
+	}
+	return shortcuts
+}
+
+func linkStore() storage.Store[storage.Link] {
+	if links == nil {
+		_ = UseStorage(config.Storage{})
+	}
+	return links
+}
+
+func pathOr(path, fallback string) string {
+	if path != "" {
+		return path
+	}
+	return fallback
+}
+
+// MigrateLegacyFiles copies the shortcuts and links files that older
+// versions read from the working directory, found in dir, to where the
+// stores keep them now. Files the user already has are never replaced. It
+// returns the paths written.
+func MigrateLegacyFiles(dir string, cfg config.Storage) ([]string, error) {
+	if cfg.Backend != "" && cfg.Backend != "file" {
+		return nil, fmt.Errorf("older versions kept flat files, switch storage.backend to file to migrate them")
+	}
+	paths := config.ResolvePaths()
+	var written []string
+	for _, file := range []struct{ name, path string }{
+		{"shortcuts", pathOr(cfg.Shortcuts, paths.UserDataFile("shortcuts"))},
+		{"links", pathOr(cfg.Links, paths.UserDataFile("links"))},
+	} {
+		if _, err := os.Stat(file.path); err == nil {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, file.name))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return written, err
+		}
+		if err := storage.WriteFileAtomic(file.path, data, 0644); err != nil {
+			return written, fmt.Errorf("error copying %s to %s: %v", file.name, file.path, err)
+		}
+		written = append(written, file.path)
+	}
+	return written, nil
+}
@@ -0,0 +1,191 @@
+package utils
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// placeholderPattern matches template placeholders such as {1}, {repo} or
+// {branch:main}.
+var placeholderPattern = regexp.MustCompile(`\{([A-Za-z0-9_]+)(?::([^{}]*))?\}`)
+
+type placeholder struct {
+	name       string
+	defaultVal string
+	hasDefault bool
+}
+
+// IsTemplate reports whether a shortcut URL contains placeholders.
+func IsTemplate(template string) bool {
+	return placeholderPattern.MatchString(template)
+}
+
+// ExpandTemplate fills the placeholders of a shortcut template with args.
+//
+// Positional placeholders ({1}, {2}, ...) take the positional arguments in
+// order, while named placeholders ({owner}) take "owner=value" arguments.
+// Positional arguments left over once the numbered placeholders are filled
+// are given to the named placeholders in order of appearance. A placeholder
+// can have a default value, e.g. {branch:main}. Values are escaped for the
+// part of the URL they end up in.
+func ExpandTemplate(template string, args []string) (string, error) {
+	placeholders := parsePlaceholders(template)
+
+	values := make(map[string]string)
+	var positional []string
+	for _, arg := range args {
+		parts := strings.SplitN(arg, "=", 2)
+		if len(parts) == 2 {
+			if _, named := placeholders[parts[0]]; named {
+				values[parts[0]] = parts[1]
+				continue
+			}
+		}
+		positional = append(positional, arg)
+	}
+
+	// Numbered placeholders first, in numeric order even with gaps such as
+	// {1} and {3}, then the named ones still missing a value
+	var order []string
+	for name := range placeholders {
+		if _, err := strconv.Atoi(name); err == nil {
+			order = append(order, name)
+		}
+	}
+	sort.Slice(order, func(i, j int) bool {
+		a, _ := strconv.Atoi(order[i])
+		b, _ := strconv.Atoi(order[j])
+		return a < b || (a == b && order[i] < order[j])
+	})
+	for _, match := range placeholderPattern.FindAllStringSubmatch(template, -1) {
+		name := match[1]
+		if _, err := strconv.Atoi(name); err != nil && !Contains(order, name) {
+			order = append(order, name)
+		}
+	}
+
+	for _, name := range order {
+		if len(positional) == 0 {
+			break
+		}
+		if _, ok := values[name]; ok {
+			continue
+		}
+		values[name] = positional[0]
+		positional = positional[1:]
+	}
+	if len(positional) > 0 {
+		return "", fmt.Errorf("too many arguments for %s: %s", template, strings.Join(positional, " "))
+	}
+
+	var missing []string
+	for _, name := range order {
+		if _, ok := values[name]; !ok && !placeholders[name].hasDefault {
+			missing = append(missing, "{"+name+"}")
+		}
+	}
+	if len(missing) > 0 {
+		return "", fmt.Errorf("missing value for %s in %s", strings.Join(missing, ", "), template)
+	}
+
+	queryStart := strings.Index(template, "?")
+	var expanded strings.Builder
+	last := 0
+	for _, loc := range placeholderPattern.FindAllStringSubmatchIndex(template, -1) {
+		name := template[loc[2]:loc[3]]
+		value, ok := values[name]
+		if !ok {
+			value = placeholders[name].defaultVal
+		}
+
+		expanded.WriteString(template[last:loc[0]])
+		if queryStart >= 0 && loc[0] > queryStart {
+			expanded.WriteString(url.QueryEscape(value))
+		} else {
+			expanded.WriteString(url.PathEscape(value))
+		}
+		last = loc[1]
+	}
+	expanded.WriteString(template[last:])
+
+	return expanded.String(), nil
+}
+
+func parsePlaceholders(template string) map[string]placeholder {
+	placeholders := make(map[string]placeholder)
+	for _, match := range placeholderPattern.FindAllStringSubmatchIndex(template, -1) {
+		p := placeholder{name: template[match[2]:match[3]]}
+		if match[4] >= 0 {
+			p.defaultVal = template[match[4]:match[5]]
+			p.hasDefault = true
+		}
+		if existing, ok := placeholders[p.name]; ok && existing.hasDefault {
+			continue
+		}
+		placeholders[p.name] = p
+	}
+	return placeholders
+}
+
+// Target is a URL resolved from a target of the command line.
+type Target struct {
+	Name string   // the shortcut or URL as typed
+	Args []string // the arguments of a template
+	URL  string
+}
+
+// ResolveTargets resolves every URL and shortcut of a command line, templates
+// taking the arguments that follow them. A template takes at least as many
+// arguments as it has placeholders without a default, and at most one per
+// placeholder, stopping at the first argument that is a target itself.
+// Targets that are neither URLs nor shortcuts are returned as unknown, and
+// nothing is resolved then.
+func ResolveTargets(args []string, shortcuts, localShortcuts map[string]string) ([]Target, []string, error) {
+	var targets []Target
+	var unknown []string
+	for i := 0; i < len(args); i++ {
+		url, ok := ResolveShortcut(args[i], shortcuts, localShortcuts)
+		if !ok {
+			unknown = append(unknown, args[i])
+			continue
+		}
+
+		target := Target{Name: args[i], URL: url}
+		if IsTemplate(url) {
+			placeholders := parsePlaceholders(url)
+			required := 0
+			for _, p := range placeholders {
+				if !p.hasDefault {
+					required++
+				}
+			}
+			for i+1 < len(args) && len(target.Args) < len(placeholders) {
+				if _, isTarget := ResolveShortcut(args[i+1], shortcuts, localShortcuts); isTarget && len(target.Args) >= required {
+					break
+				}
+				i++
+				target.Args = append(target.Args, args[i])
+			}
+		}
+		targets = append(targets, target)
+	}
+	if len(unknown) > 0 {
+		return nil, unknown, nil
+	}
+
+	for i, target := range targets {
+		if !IsTemplate(target.URL) {
+			continue
+		}
+		expanded, err := ExpandTemplate(target.URL, target.Args)
+		if err != nil {
+			return nil, nil, err
+		}
+		targets[i].URL = expanded
+	}
+	return targets, nil, nil
+}
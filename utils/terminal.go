@@ -0,0 +1,67 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Terminal is the terminal in raw mode, for full-screen interfaces. It is
+// set up with the stty command, so it only works where stty does.
+type Terminal struct {
+	saved string // stty settings restored by Close
+}
+
+// OpenTerminal switches the terminal of stdin to raw mode and to the
+// alternate screen.
+func OpenTerminal() (*Terminal, error) {
+	if !IsTerminal(os.Stdin) {
+		return nil, fmt.Errorf("stdin is not a terminal")
+	}
+	saved, err := stty("-g")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := stty("raw", "-echo"); err != nil {
+		return nil, err
+	}
+	t := &Terminal{saved: strings.TrimSpace(saved)}
+	fmt.Fprint(os.Stdout, "\x1b[?1049h")
+	return t, nil
+}
+
+// Read reads the keys typed, as bytes.
+func (t *Terminal) Read(p []byte) (int, error) {
+	return os.Stdin.Read(p)
+}
+
+// Size returns the rows and columns of the terminal, 24x80 when unknown.
+func (t *Terminal) Size() (int, int) {
+	var rows, cols int
+	out, err := stty("size")
+	if err != nil {
+		return 24, 80
+	}
+	if n, _ := fmt.Sscan(out, &rows, &cols); n != 2 || rows <= 0 || cols <= 0 {
+		return 24, 80
+	}
+	return rows, cols
+}
+
+// Close leaves the alternate screen and restores the terminal settings.
+func (t *Terminal) Close() error {
+	fmt.Fprint(os.Stdout, "\x1b[?25h\x1b[?1049l")
+	_, err := stty(t.saved)
+	return err
+}
+
+func stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("error running stty %s: %v", strings.Join(args, " "), err)
+	}
+	return string(out), nil
+}
@@ -7,289 +7,353 @@ import (
 	"io"
 	"os"
 	"os/exec"
-	"path/filepath"
+	"regexp"
 	"runtime"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/404answernotfound/browsir/config"
+	"github.com/404answernotfound/browsir/storage"
 	"github.com/PuerkitoBio/goquery"
 )
 
 func LoadLocalShortcuts() map[string]string {
-	shortcuts := make(map[string]string)
-
-	// First check system config directory
-	shortcutsPath := "/etc/browsir/shortcuts"
+	store := shortcutStore()
+	if err := store.Load(); err != nil {
+		return make(map[string]string)
+	}
+	return store.List()
+}
 
-	// Finally check current directory
-	if _, err := os.Stat(shortcutsPath); os.IsNotExist(err) {
-		shortcutsPath = "shortcuts"
+// LoadLinks reads the link library.
+func LoadLinks() (map[string]storage.Link, error) {
+	store := linkStore()
+	if err := store.Load(); err != nil {
+		return nil, fmt.Errorf("error loading links: %v", err)
 	}
+	return store.List(), nil
+}
 
-	data, err := os.ReadFile(shortcutsPath)
-	if err != nil {
-		return shortcuts
+// SaveLink adds a link to the library, in the "general" category when it has
+// none.
+func SaveLink(link string, record storage.Link) error {
+	if len(record.Categories) == 0 {
+		record.Categories = []string{"general"}
+	}
+	now := time.Now()
+	if record.Created.IsZero() {
+		record.Created = now
+	}
+	if record.Updated.IsZero() {
+		record.Updated = now
 	}
 
-	lines := strings.Split(string(data), "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" || strings.HasPrefix(line, "#") {
-			continue
-		}
-		parts := strings.SplitN(line, "=", 2)
-		if len(parts) == 2 {
-			shortcuts[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
-		}
+	store := linkStore()
+	if _, exists := store.Get(link); exists {
+		fmt.Printf("link already exists with url %s\n", link)
+		return nil
 	}
-	return shortcuts
+
+	// Writing https://somelink.com|some,categories|{"title":...}
+	return store.Put(link, record)
 }
 
-// TODO: Add other paths
-func LoadLinks() map[string]string {
-	var links = make(map[string]string)
-	var linksPath string
+// GetLink returns the record of a link of the library.
+func GetLink(link string) (storage.Link, bool) {
+	return linkStore().Get(link)
+}
 
-	// First check system config directory
-	linksPath = "/etc/browsir/links"
+// UpdateLink lets edit change the record of a link, marking it updated.
+func UpdateLink(link string, edit func(record *storage.Link)) error {
+	store := linkStore()
+	record, exists := store.Get(link)
+	if !exists {
+		return fmt.Errorf("link '%v' not found", link)
+	}
+	edit(&record)
+	record.Updated = time.Now()
+	return store.Put(link, record)
+}
 
-	if _, err := os.Stat(linksPath); os.IsNotExist(err) {
-		linksPath = "links"
+// MoveLink stores the record of a link under a new URL, merging it with the
+// record already there, if any.
+func MoveLink(from, to string) error {
+	store := linkStore()
+	record, exists := store.Get(from)
+	if !exists {
+		return fmt.Errorf("link '%v' not found", from)
+	}
+	if existing, ok := store.Get(to); ok {
+		for _, c := range record.Categories {
+			if !Contains(existing.Categories, c) {
+				existing.Categories = append(existing.Categories, c)
+			}
+		}
+		existing.OpenCount += record.OpenCount
+		record = existing
 	}
+	record.Updated = time.Now()
 
-	data, err := os.ReadFile(linksPath)
-	if err != nil {
-		return links
+	if err := store.Put(to, record); err != nil {
+		return err
 	}
+	return store.Delete(from)
+}
 
-	lines := strings.Split(string(data), "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" || strings.HasPrefix(line, "#") {
-			continue
+// recordOpened counts the visits of the links of the library among urls,
+// and adds urls to the history. URLs that are not saved links are only
+// added to the history, and the counts go to the per-user links file.
+//
+// It is best effort: the browser is already open, so errors are ignored,
+// and the file is not locked. Every link is read again right before its
+// count is written, but two browsir opening links at the same time can
+// still lose a visit, or a link saved by the other.
+func recordOpened(urls []string) {
+	now := time.Now()
+	recordHistory(urls, now)
+
+	store := linkStore()
+	for _, url := range urls {
+		if err := store.Load(); err != nil {
+			return
 		}
-		parts := strings.SplitN(line, "|", 2)
-		if len(parts) == 2 {
-			links[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+		for _, key := range []string{url, strings.TrimPrefix(url, "https://")} {
+			if record, ok := store.Get(key); ok {
+				record.LastOpened = now
+				record.OpenCount++
+				_ = store.Put(key, record)
+				break
+			}
 		}
 	}
-
-	return links
 }
 
-func SaveLink(link string, categories string) error {
-
-	linksPath := "/etc/browsir/links"
-
-	categoriesSlice := strings.Split(categories, ",")
-	var categoriesToString string
-
-	if len(categoriesSlice) == 0 {
-		categoriesToString = "general"
+func SaveLocalShortcut(shortcut, url string) error {
+	store := shortcutStore()
+	for name, existing := range store.List() {
+		if existing == url {
+			fmt.Printf("shortcut already exists with url %s\n", name)
+			return nil
+		}
 	}
 
-	categoriesToString = strings.Join(categoriesSlice, ",")
-
-	f, err := os.OpenFile(linksPath, os.O_APPEND|os.O_CREATE|os.O_RDWR, 0644)
-	CheckDuplicates(f, link, 0, "link", "|")
-
-	if err == nil {
-		defer f.Close()
-		// Writing to file https://somelink.com|some category
-		_, err = fmt.Fprintf(f, "%s|%s\n", link, categoriesToString)
+	if err := store.Put(shortcut, url); err != nil {
 		return err
 	}
+	fmt.Printf("Shortcut %s correctly saved\n", shortcut)
+	return nil
+}
 
-	f, err = os.OpenFile("links", os.O_APPEND|os.O_CREATE|os.O_RDWR, 0644)
-	CheckDuplicates(f, link, 0, "link", "|")
-
+func RemoveLocalShortcut(shortcut string) error {
+	err := shortcutStore().Delete(shortcut)
+	if errors.Is(err, storage.ErrNotFound) {
+		return fmt.Errorf("shortcut '%v' not found", shortcut)
+	}
+	if errors.Is(err, storage.ErrReadOnly) {
+		return fmt.Errorf("shortcut '%v' is system-wide and read-only, override it with: browsir add shortcut %v <url>", shortcut, shortcut)
+	}
 	if err != nil {
 		return err
 	}
-	defer f.Close()
 
-	_, err = fmt.Fprintf(f, "%s|%s\n", link, categoriesToString)
-	return err
+	fmt.Printf("Shortcut %s correctly removed!\n", shortcut)
+	return nil
 }
 
-func SaveLocalShortcut(shortcut, url string) error {
-
-	// First try system config directory
-	shortcutsPath := "/etc/browsir/shortcuts"
-
-	f, err := os.OpenFile(shortcutsPath, os.O_APPEND|os.O_CREATE|os.O_RDWR, 0644)
-	CheckDuplicates(f, url, 1, "shortcut", "=")
+// RemoveLinks drops every given link from the links store.
+func RemoveLinks(links []string) error {
+	if len(links) == 0 {
+		return nil
+	}
 
-	if err == nil {
-		defer f.Close()
-		_, err = fmt.Fprintf(f, "%s=%s\n", shortcut, url)
-		if err == nil {
-			fmt.Printf("Shortcut %s correctly saved\n", shortcut)
-		}
-		return err
+	err := linkStore().Delete(links...)
+	if errors.Is(err, storage.ErrNotFound) {
+		return fmt.Errorf("link '%v' not found", links[0])
 	}
+	if errors.Is(err, storage.ErrReadOnly) {
+		return fmt.Errorf("%v, system-wide links can not be removed", err)
+	}
+	return err
+}
 
-	// Finally try current directory
-	f, err = os.OpenFile("shortcuts", os.O_APPEND|os.O_CREATE|os.O_RDWR, 0644)
-	CheckDuplicates(f, url, 1, "shortcut", "=")
+func GetBrowserPath(browserName string) (string, error) {
+	browser, ok := LookupBrowser(browserName)
+	if !ok {
+		return "", fmt.Errorf("unsupported browser %s on %s", browserName, runtime.GOOS)
+	}
 
-	if err == nil {
-		defer f.Close()
-		_, err = fmt.Fprintf(f, "%s=%s\n", shortcut, url)
-		if err == nil {
-			fmt.Printf("Shortcut %s correctly saved", shortcut)
-		}
-		return err
+	path := executable(browser)
+	if path == "" {
+		return "", fmt.Errorf("no executable configured for browser %s", browserName)
 	}
+	return path, nil
+}
 
-	return err
+func OpenBrowser(browserName string, profile config.Profile, url string) error {
+	var urls []string
+	if url != "" {
+		urls = append(urls, url)
+	}
+	return OpenURLs(browserName, profile, urls, LaunchOptions{})
 }
 
-func RemoveLocalShortcut(shortcut string) error {
-	shortcutsPath := "/etc/browsir/shortcuts"
+// LaunchOptions change how OpenURLs launches the browser.
+type LaunchOptions struct {
+	NewWindow  bool
+	Private    bool
+	App        bool   // only one URL, in a window without browser UI
+	Kiosk      bool   // fullscreen
+	WindowSize string // WIDTHxHEIGHT
+}
 
-	// First open shortcut file
-	f, err := os.OpenFile(shortcutsPath, os.O_APPEND|os.O_CREATE|os.O_RDWR, 0644)
-	if err != nil {
-		return err
+// WithMode adds a launch mode, e.g. the one of a shortcut, to the options.
+func (o LaunchOptions) WithMode(mode config.LaunchMode) LaunchOptions {
+	o.NewWindow = o.NewWindow || mode.NewWindow
+	o.Private = o.Private || mode.Private
+	o.App = o.App || mode.App
+	o.Kiosk = o.Kiosk || mode.Kiosk
+	if o.WindowSize == "" {
+		o.WindowSize = mode.WindowSize
 	}
-	defer f.Close()
+	return o
+}
 
-	// Create a temp empty file
-	tempFilePath := shortcutsPath + ".tmp"
-	tempFile, err := os.Create(tempFilePath)
+// OpenURLs starts a single browser process opening every URL.
+func OpenURLs(browserName string, profile config.Profile, urls []string, opts LaunchOptions) error {
+	browserPath, args, targets, err := browserCommand(browserName, profile, urls, opts)
 	if err != nil {
 		return err
 	}
-	defer tempFile.Close()
-	
-	scanner := bufio.NewScanner(f)
-	writer := bufio.NewWriter(tempFile)
-	
-	// Loop the shortcut file and copy in the temp only the ones that don't match the requested one
-	found := false
-	for scanner.Scan() {
-		line := scanner.Text()
-		if strings.HasPrefix(line, shortcut + "=") {
-			found = true
-			continue
-		}
-		fmt.Fprintln(writer, line)
-	}
 
-	// if not found returns related error
-	if !found {
-		return fmt.Errorf("shortcut '%v' not found", shortcut)
-	}
-	if err := scanner.Err(); err != nil {
-		return err
+	cmd := exec.Command(browserPath, args...)
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start browser: %v", err)
 	}
-	
-	writer.Flush()
-	
-	// Replace the temp file as the new shortcut file
-	if err := os.Rename(tempFilePath, shortcutsPath); err != nil {
-		return err
+
+	// Private windows leave no trace, in browsir's history as in the browser's
+	if !opts.Private {
+		recordOpened(targets)
 	}
+	return nil
+}
 
-	err = os.Remove(tempFilePath)
+// CheckLaunch fails where OpenURLs would before starting the browser, on a
+// missing browser or options it does not support, without starting it.
+func CheckLaunch(browserName string, profile config.Profile, urls []string, opts LaunchOptions) error {
+	_, _, _, err := browserCommand(browserName, profile, urls, opts)
+	return err
+}
 
+// browserCommand returns the executable and the arguments opening urls,
+// and the urls themselves with their scheme.
+func browserCommand(browserName string, profile config.Profile, urls []string, opts LaunchOptions) (string, []string, []string, error) {
+	browserPath, err := GetBrowserPath(browserName)
 	if err != nil {
-		return err
+		return "", nil, nil, err
 	}
+	browser, _ := LookupBrowser(browserName)
 
-	fmt.Printf("Shortcut %s correctly removed!\n", shortcut)
-	return nil
-}
-
-func GetBrowserPath(browserName string) (string, error) {
-	switch runtime.GOOS {
-	case "darwin":
-		switch browserName {
-		case "chrome":
-			return "/Applications/Google Chrome.app/Contents/MacOS/Google Chrome", nil
-		case "brave":
-			return "/Applications/Brave Browser.app/Contents/MacOS/Brave Browser", nil
-		case "arc":
-			return "/Applications/Arc.app/Contents/MacOS/Arc", nil
-		case "zen":
-			return "/Applications/Zen.app/Contents/MacOS/zen", nil
-		case "firefox":
-			return "/Applications/Firefox.app/Contents/MacOS/firefox", nil
-		case "firefox-developer-edition":
-			return "/Applications/Firefox Developer Edition.app/Contents/MacOS/firefox", nil
-		}
-	case "linux":
-		switch browserName {
-		case "firefox":
-			return "firefox", nil
-		case "vivaldi":
-			return "vivaldi", nil
-		case "chrome":
-			return "google-chrome", nil
-		case "brave":
-			return "brave-browser", nil
-		}
-	case "windows":
-		switch browserName {
-		case "chrome":
-			return filepath.Join(os.Getenv("ProgramFiles"), "Google", "Chrome", "Application", "chrome.exe"), nil
-		case "brave":
-			return filepath.Join(
-				os.Getenv("ProgramFiles"),
-				"BraveSoftware",
-				"Brave-Browser",
-				"Application",
-				"brave.exe",
-			), nil
+	targets := make([]string, 0, len(urls))
+	for _, url := range urls {
+		if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+			url = "https://" + url
 		}
+		targets = append(targets, url)
 	}
-	return "", fmt.Errorf("unsupported browser %s on %s", browserName, runtime.GOOS)
-}
 
-func OpenBrowser(browserName string, profile config.Profile, url string) error {
-	browserPath, err := GetBrowserPath(browserName)
+	args, err := launchArgs(browserName, browser, profile, targets, opts)
 	if err != nil {
-		return err
+		return "", nil, nil, err
 	}
+	return browserPath, args, targets, nil
+}
 
-	var args []string
-	if browserName == "firefox" || browserName == "firefox-developer-edition" || browserName == "zen" {
-		args = []string{"-profile", profile.ProfileDir}
-	} else {
-		args = []string{"--profile-directory=" + profile.ProfileDir}
+// launchArgs translates the profile, the launch options and the URLs into
+// the arguments of a browser, failing on the options it does not support or
+// that do not go together.
+func launchArgs(browserName string, browser config.Browser, profile config.Profile, urls []string, opts LaunchOptions) ([]string, error) {
+	switch {
+	case opts.App && len(urls) != 1:
+		return nil, fmt.Errorf("--app opens exactly one URL, got %d", len(urls))
+	case opts.App && opts.NewWindow:
+		return nil, fmt.Errorf("--app and --new-window can not be combined, apps always get their own window")
+	case opts.Kiosk && opts.WindowSize != "":
+		return nil, fmt.Errorf("--kiosk and --window-size can not be combined, kiosk windows are fullscreen")
 	}
 
-	if url != "" {
-		if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
-			url = "https://" + url
+	var args []string
+	if profile.ProfileDir != "" {
+		args = expandFlag(browser.ProfileFlag, map[string]string{"profile": profile.ProfileDir})
+	}
+
+	modes := []struct {
+		enabled bool
+		flag    string
+		name    string
+	}{
+		{opts.Private, browser.IncognitoFlag, "private windows"},
+		{opts.NewWindow, browser.NewWindowFlag, "new windows"},
+		{opts.Kiosk, browser.KioskFlag, "kiosk mode"},
+		{opts.App, browser.AppFlag, "app mode"},
+		{opts.WindowSize != "", browser.WindowSizeFlag, "setting the window size"},
+	}
+	for _, mode := range modes {
+		if mode.enabled && mode.flag == "" {
+			return nil, fmt.Errorf("browser %s does not support %s", browserName, mode.name)
 		}
-		args = append(args, url)
 	}
 
-	cmd := exec.Command(browserPath, args...)
-	if err := cmd.Start(); err != nil {
-		return fmt.Errorf("failed to start browser: %v", err)
+	if opts.Private {
+		args = append(args, expandFlag(browser.IncognitoFlag, nil)...)
 	}
+	if opts.NewWindow {
+		args = append(args, expandFlag(browser.NewWindowFlag, nil)...)
+	}
+	if opts.Kiosk {
+		args = append(args, expandFlag(browser.KioskFlag, nil)...)
+	}
+	if opts.WindowSize != "" {
+		width, height, err := ParseWindowSize(opts.WindowSize)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, expandFlag(browser.WindowSizeFlag, map[string]string{"width": width, "height": height})...)
+	}
+	if opts.App {
+		return append(args, expandFlag(browser.AppFlag, map[string]string{"url": urls[0]})...), nil
+	}
+	return append(args, urls...), nil
+}
 
-	return nil
+// ParseWindowSize splits a WIDTHxHEIGHT window size, e.g. 1280x800.
+func ParseWindowSize(size string) (string, string, error) {
+	width, height, ok := strings.Cut(strings.ToLower(size), "x")
+	if ok {
+		w, errW := strconv.Atoi(width)
+		h, errH := strconv.Atoi(height)
+		if errW == nil && errH == nil && w > 0 && h > 0 {
+			return width, height, nil
+		}
+	}
+	return "", "", fmt.Errorf("invalid window size: %s, use WIDTHxHEIGHT, e.g. 1280x800", size)
 }
 
 func PrintUsage(profiles []config.Profile, shortcuts map[string]string, localShortcuts map[string]string) {
 	fmt.Printf("browsir v1.0.0\n\n")
-	fmt.Println("Usage: browsir [profile] [url|shortcut]")
+	fmt.Println("Usage: browsir [profile] [url|shortcut]...")
 	fmt.Println("\nProfiles:")
 	for _, p := range profiles {
 		fmt.Printf("  %-12s - %s\n", p.Name, p.Description)
 	}
 	fmt.Println("\nShortcuts:")
-	for shortcut, url := range shortcuts {
-		fmt.Printf("  %-12s -> %s\n", shortcut, url)
+	for _, shortcut := range storage.Keys(shortcuts) {
+		fmt.Printf("  %-12s -> %s\n", shortcut, shortcuts[shortcut])
 	}
 	if len(localShortcuts) > 0 {
 		fmt.Println("\nLocal Shortcuts:")
-		for shortcut, url := range localShortcuts {
-			fmt.Printf("  %-12s -> %s\n", shortcut, url)
+		for _, shortcut := range storage.Keys(localShortcuts) {
+			fmt.Printf("  %-12s -> %s\n", shortcut, localShortcuts[shortcut])
 		}
 	}
 	fmt.Println("\nExamples:")
@@ -303,15 +367,36 @@ func PrintUsage(profiles []config.Profile, shortcuts map[string]string, localSho
 	fmt.Println("  -ls, --list-shortcuts # List all shortcuts")
 	fmt.Println("  -p, --profiles        # List all profiles")
 	fmt.Println("  -q        		     # Search the web with a query")
-	fmt.Println("  -se, --search-engine  # Specify search engine (google, duckduckgo, brave)")
+	fmt.Println("  -se, --search-engine  # Specify search engine (google, duckduckgo, brave or any in search_engines)")
+	fmt.Println("  -o, --output          # Print listings as json, yaml, tsv or table")
 
-	fmt.Println("   browsir add link <link> -c <categories>	# Add a link with categories")
+	fmt.Println("   browsir help <command>					# Show the help of a command")
+	fmt.Println("   browsir completion bash|zsh|fish			# Print the shell completion script")
+	fmt.Println("   browsir search [--engine=<engine>] <query>	# Search the web with default_profile")
+	fmt.Println("   browsir add link <link> -c <categories>	# Add a link with categories, --title and --note")
 	fmt.Println("   browsir add shortcut <shortcut> <url>	# Add a local shortcut, do not include http:// or https://")
 	fmt.Println("   browsir rm link <link>					# Remove a link")
+	fmt.Println("   browsir rm link --category=<category>	# Remove every link in a category")
+	fmt.Println("   browsir rm link --match=<glob>			# Remove every link matching a glob")
 	fmt.Println("   browsir rm shortcut <shortcut>			# Remove a local shortcut")
 	fmt.Println("   browsir list links						# List all links")
 	fmt.Println("   browsir list all						# List all links and categories")
-	fmt.Println("   browsir preview <link>					# Preview a link")
+	fmt.Println("   browsir preview <link> [--json] [--refresh]	# Preview a link: title, OpenGraph, reading time...")
+	fmt.Println("   browsir cache stats|clear			# Inspect or clear the preview cache")
+	fmt.Println("   browsir links search <term>... [--fuzzy]	# Find links by URL and category")
+	fmt.Println("   browsir links check [--update] [--tag-dead]	# Find dead and moved links")
+	fmt.Println("   browsir import <file> [--dry-run]		# Import browser bookmarks as links")
+	fmt.Println("   browsir export --format=html|md|csv|opml	# Export links, --category and --out to filter and save")
+	fmt.Println("   browsir profiles discover [--write]		# Find browser profiles, optionally saving them")
+	fmt.Println("   browsir pick [query]					# Fuzzy find a shortcut, link or recent URL to open or copy")
+	fmt.Println("   browsir menu [--launcher=rofi]			# The same in rofi, dmenu, wofi or fzf, then pick the profile")
+	fmt.Println("   browsir open <url|shortcut>				# Open with the profile picked by the routes")
+	fmt.Println("   browsir <profile> - | --clipboard		# Open the URLs read from stdin or the clipboard")
+	fmt.Println("   browsir <profile> <url|shortcut> --private	# Also --new-window, --app, --kiosk and --window-size=WxH")
+	fmt.Println("   browsir route test <url>				# Show which route matches a URL")
+	fmt.Println("   browsir handle <url>					# Open a link clicked in another application")
+	fmt.Println("   browsir session <name>					# Open every URL of a session")
+	fmt.Println("   browsir session save <name> <url>...	# Save URLs and shortcuts as a session")
 }
 
 func PrintProfiles(profiles []config.Profile) {
@@ -321,6 +406,22 @@ func PrintProfiles(profiles []config.Profile) {
 	}
 }
 
+// ResolveShortcut turns a target into a URL. Targets containing a dot or a
+// protocol are URLs already; the others are looked up in the local shortcuts
+// first and then in the config ones.
+func ResolveShortcut(target string, shortcuts, localShortcuts map[string]string) (string, bool) {
+	if strings.Contains(target, ".") || strings.HasPrefix(target, "http") {
+		return target, true
+	}
+	if localURL, exists := localShortcuts[target]; exists {
+		return localURL, true
+	}
+	if configURL, exists := shortcuts[target]; exists {
+		return configURL, true
+	}
+	return "", false
+}
+
 func FindSimilarShortcuts(input string, shortcuts, localShortcuts map[string]string) []string {
 	var similar []string
 	for shortcut := range shortcuts {
@@ -337,64 +438,94 @@ func FindSimilarShortcuts(input string, shortcuts, localShortcuts map[string]str
 }
 
 func PromptYesNo(prompt string) bool {
-	reader := bufio.NewReader(os.Stdin)
+	return PromptYesNoFrom(os.Stdin, prompt)
+}
+
+// PromptYesNoFrom asks a yes or no question, reading the answer from r, e.g.
+// the terminal when stdin is used for data.
+func PromptYesNoFrom(r io.Reader, prompt string) bool {
+	reader := bufio.NewReader(r)
 	for {
 		fmt.Printf("%s (y/n): ", prompt)
-		response, _ := reader.ReadString('\n')
+		response, err := reader.ReadString('\n')
 		response = strings.ToLower(strings.TrimSpace(response))
 		if response == "y" || response == "yes" {
 			return true
 		}
-		if response == "n" || response == "no" {
+		// Without an answer, e.g. when stdin is closed, nothing is done
+		if response == "n" || response == "no" || err != nil {
 			return false
 		}
 	}
 }
 
-func PrintLocalShortcuts(shortcuts map[string]string) {
-	for shortcut, url := range shortcuts {
-		fmt.Printf("  %-12s -> %s\n", shortcut, url)
+// PromptChoice asks to pick one of the options by number and returns its
+// index.
+func PromptChoice(prompt string, options []string) (int, error) {
+	if len(options) == 0 {
+		return 0, errors.New("nothing to choose from")
 	}
-}
 
-func Search(browserName string, profile config.Profile, searchEngine string, searchTerm string) error {
+	for i, option := range options {
+		fmt.Printf("  %2d) %s\n", i+1, option)
+	}
 
-	var url string
-	if searchTerm != "" {
-		switch searchEngine {
-		case "google":
-			url = fmt.Sprintf("google.com/search?q=%s", searchTerm)
-		case "duckduckgo":
-			url = fmt.Sprintf("duckduckgo.com//?q=%s", searchTerm)
-		case "brave":
-			url = fmt.Sprintf("search.brave.com/search?q=%s", searchTerm)
-		default:
-			url = fmt.Sprintf("google.com/search?q=%s", searchTerm)
+	reader := bufio.NewReader(os.Stdin)
+	for {
+		fmt.Printf("%s [1-%d]: ", prompt, len(options))
+		response, err := reader.ReadString('\n')
+		if err != nil && strings.TrimSpace(response) == "" {
+			return 0, errors.New("no choice made")
+		}
+		choice, err := strconv.Atoi(strings.TrimSpace(response))
+		if err == nil && choice >= 1 && choice <= len(options) {
+			return choice - 1, nil
 		}
-		url = "https://" + url
 	}
+}
 
-	OpenBrowser(browserName, profile, url)
+// IsTerminal reports whether f is connected to a terminal.
+func IsTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
 
-	return nil
+func PrintLocalShortcuts(shortcuts map[string]string) {
+	for _, shortcut := range storage.Keys(shortcuts) {
+		fmt.Printf("  %-12s -> %s\n", shortcut, shortcuts[shortcut])
+	}
 }
 
-func GetFlags(args []string) map[string]string {
-	flags := make(map[string]string)
-	if args == nil {
-		return flags
+func Search(browserName string, profile config.Profile, engines map[string]string, searchEngine string, searchTerm string) error {
+	url, err := SearchURL(engines, searchEngine, searchTerm)
+	if err != nil {
+		return err
 	}
-	for _, arg := range args {
-		if strings.HasPrefix(arg, "-") || strings.HasPrefix(arg, "--") {
-			parts := strings.SplitN(arg, "=", 2)
-			if len(parts) == 2 {
-				flags[parts[0]] = parts[1]
-			} else {
-				flags[arg] = ""
-			}
+
+	return OpenBrowser(browserName, profile, url)
+}
+
+// SearchURL builds the URL searching searchTerm with the given engine. A
+// leading bang, as in "!gh cobra", picks the engine inline instead.
+func SearchURL(engines map[string]string, searchEngine string, searchTerm string) (string, error) {
+	searchTerm = strings.TrimSpace(searchTerm)
+	if strings.HasPrefix(searchTerm, "!") {
+		bang, rest, _ := strings.Cut(searchTerm[1:], " ")
+		if _, ok := engines[bang]; !ok {
+			return "", fmt.Errorf("unknown search engine: %s", bang)
 		}
+		searchEngine, searchTerm = bang, strings.TrimSpace(rest)
+	}
+
+	template, ok := engines[searchEngine]
+	if !ok {
+		return "", fmt.Errorf("unknown search engine: %s", searchEngine)
+	}
+	if !strings.Contains(template, "{q}") {
+		return "", fmt.Errorf("search engine %s has no {q} placeholder", searchEngine)
 	}
-	return flags
+
+	return ExpandTemplate(template, []string{"q=" + searchTerm})
 }
 
 func Contains(args []string, value string) bool {
@@ -437,10 +568,21 @@ func FindHtmlNode(doc *goquery.Document, search []string) []string {
 	return values
 }
 
-func CheckInputArgs (currentArgs, expectedArgs int) error {
-	if currentArgs < expectedArgs {
-		fmt.Printf("You provided %d arguments, while %d are needed.\nPlease, see --help flag to check usage for add command\n", currentArgs, expectedArgs)
-		return errors.New("not enough arguments")
+// MatchGlob reports whether s matches pattern, where '*' matches any sequence
+// of characters (slashes included) and '?' matches exactly one character.
+func MatchGlob(pattern, s string) bool {
+	var expr strings.Builder
+	expr.WriteString("^")
+	for _, r := range pattern {
+		switch r {
+		case '*':
+			expr.WriteString(".*")
+		case '?':
+			expr.WriteString(".")
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(r)))
+		}
 	}
-	return nil
+	expr.WriteString("$")
+	return regexp.MustCompile(expr.String()).MatchString(s)
 }
@@ -1,8 +1,23 @@
 package utils
 
 import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/404answernotfound/browsir/config"
+	"github.com/404answernotfound/browsir/storage"
+	"gopkg.in/yaml.v3"
 )
 
 var HOME = os.Getenv("HOME")
@@ -12,3 +27,1041 @@ func UtilsTest(t *testing.T) {
 		// TODO: Implement test for LoadLocalShortcuts
 	})
 }
+
+func TestLinksWithMemoryStore(t *testing.T) {
+	SetStores(
+		storage.NewMemoryStore(map[string]string{"gh": "github.com"}),
+		storage.NewMemoryStore(map[string]storage.Link{"https://go.dev": {Categories: []string{"go"}}}),
+	)
+	SetHistory(storage.NewMemoryStore(map[string]time.Time{}))
+	t.Cleanup(func() { SetStores(nil, nil); SetHistory(nil) })
+
+	if got := LoadLocalShortcuts()["gh"]; got != "github.com" {
+		t.Errorf("got %v, want %v", got, "github.com")
+	}
+
+	if err := SaveLink("https://github.com", storage.Link{Title: "GitHub"}); err != nil {
+		t.Fatalf("Error saving link: %v", err)
+	}
+	loaded, err := LoadLinks()
+	if err != nil {
+		t.Fatalf("Error loading links: %v", err)
+	}
+	got := loaded["https://github.com"]
+	if len(got.Categories) != 1 || got.Categories[0] != "general" || got.Title != "GitHub" || got.Created.IsZero() {
+		t.Errorf("got %+v, want the general category, a title and a creation time", got)
+	}
+
+	recordOpened([]string{"https://go.dev", "https://go.dev", "https://example.com"})
+	loaded, _ = LoadLinks()
+	if got := loaded["https://go.dev"]; got.OpenCount != 2 || got.LastOpened.IsZero() {
+		t.Errorf("got %v opens at %v, want 2", got.OpenCount, got.LastOpened)
+	}
+	if _, ok := loaded["https://example.com"]; ok {
+		t.Errorf("got a link saved for an opened URL, want only saved links counted")
+	}
+	recordOpened([]string{"https://go.dev"})
+	if got, want := RecentURLs(5), []string{"https://go.dev", "https://example.com"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got recent URLs %q, want %q", got, want)
+	}
+
+	if err := MoveLink("https://github.com", "https://github.com/home"); err != nil {
+		t.Fatalf("Error moving link: %v", err)
+	}
+	if got, ok := GetLink("https://github.com/home"); !ok || got.Title != "GitHub" {
+		t.Errorf("got %+v, want the moved record", got)
+	}
+
+	if err := RemoveLinks([]string{"https://go.dev", "https://github.com/home"}); err != nil {
+		t.Fatalf("Error removing links: %v", err)
+	}
+	loaded, _ = LoadLinks()
+	if got := len(loaded); got != 0 {
+		t.Errorf("got %v links, want %v", got, 0)
+	}
+}
+
+func TestLoadLinksError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "links.json")
+	if err := os.WriteFile(path, []byte(`{"entries": {`), 0644); err != nil {
+		t.Fatalf("Error writing links: %v", err)
+	}
+	SetStores(storage.NewMemoryStore(map[string]string{}), storage.NewJSONStore[storage.Link](path))
+	t.Cleanup(func() { SetStores(nil, nil) })
+
+	if links, err := LoadLinks(); err == nil {
+		t.Errorf("got %v and no error, want the parse error", links)
+	}
+}
+
+func TestMigrateLegacyFiles(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("BROWSIR_HOME", home)
+	old := t.TempDir()
+	if err := os.WriteFile(filepath.Join(old, "shortcuts"), []byte("gh=github.com\n"), 0644); err != nil {
+		t.Fatalf("Error writing shortcuts: %v", err)
+	}
+
+	written, err := MigrateLegacyFiles(old, config.Storage{})
+	if err != nil || !reflect.DeepEqual(written, []string{filepath.Join(home, "shortcuts")}) {
+		t.Fatalf("got %v and %v, want the shortcuts copied", written, err)
+	}
+	if got, _ := os.ReadFile(filepath.Join(home, "shortcuts")); string(got) != "gh=github.com\n" {
+		t.Errorf("got %q copied", got)
+	}
+
+	// The copy in the data directory is never replaced
+	if err := os.WriteFile(filepath.Join(old, "shortcuts"), []byte("gh=gitlab.com\n"), 0644); err != nil {
+		t.Fatalf("Error writing shortcuts: %v", err)
+	}
+	if written, err := MigrateLegacyFiles(old, config.Storage{}); err != nil || len(written) != 0 {
+		t.Errorf("got %v and %v on a second migration, want nothing written", written, err)
+	}
+	if got, _ := os.ReadFile(filepath.Join(home, "shortcuts")); string(got) != "gh=github.com\n" {
+		t.Errorf("got %q after a second migration", got)
+	}
+
+	if _, err := MigrateLegacyFiles(old, config.Storage{Backend: "json"}); err == nil {
+		t.Errorf("got no error migrating to the json backend")
+	}
+}
+
+func TestUseStorageLayersLinks(t *testing.T) {
+	t.Setenv("BROWSIR_HOME", t.TempDir())
+	if err := UseStorage(config.Storage{}); err != nil {
+		t.Fatalf("Error opening the stores: %v", err)
+	}
+	t.Cleanup(func() { SetStores(nil, nil) })
+	if _, ok := linkStore().(*storage.LayeredStore[storage.Link]); !ok {
+		t.Errorf("got links in %T, want them layered over the system-wide ones", linkStore())
+	}
+}
+
+func TestExpandTemplate(t *testing.T) {
+	tcs := []struct {
+		template string
+		args     []string
+		want     string
+		wantErr  bool
+	}{
+		{"jira.corp.example/browse/{1}", []string{"PROJ-123"}, "jira.corp.example/browse/PROJ-123", false},
+		{"github.com/{owner}/{repo}/pull/{pr}", []string{"owner=acme", "repo=api", "pr=42"}, "github.com/acme/api/pull/42", false},
+		{"github.com/{owner}/{repo}/pull/{pr}", []string{"acme", "api", "42"}, "github.com/acme/api/pull/42", false},
+		{"github.com/acme/{repo}/tree/{branch:main}", []string{"api"}, "github.com/acme/api/tree/main", false},
+		{"github.com/acme/{repo}/tree/{branch:main}", []string{"api", "branch=dev"}, "github.com/acme/api/tree/dev", false},
+		{"google.com/search?q={1}", []string{"a&b #c è"}, "google.com/search?q=a%26b+%23c+%C3%A8", false},
+		{"github.com/{owner}/{repo}", []string{"owner=acme"}, "", true},
+		{"jira.corp.example/browse/{1}", []string{"PROJ-1", "PROJ-2"}, "", true},
+		{"example.com/{1}/{3}", []string{"a", "b"}, "example.com/a/b", false},
+		{"example.com/{1}/{3}", []string{"a"}, "", true},
+		{"example.com/{10}/{2}", []string{"a", "b"}, "example.com/b/a", false},
+		{"example.com/{2}", []string{"a"}, "example.com/a", false},
+		{"example.com/{2}", nil, "", true},
+	}
+
+	for _, tc := range tcs {
+		got, err := ExpandTemplate(tc.template, tc.args)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("%s %v: got error %v, wantErr %v", tc.template, tc.args, err, tc.wantErr)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("%s %v: got %v, want %v", tc.template, tc.args, got, tc.want)
+		}
+	}
+}
+
+func TestResolveTargets(t *testing.T) {
+	shortcuts := map[string]string{
+		"mail": "gmail.com",
+		"cal":  "calendar.google.com",
+		"jira": "jira.corp.example/browse/{1}",
+		"tree": "github.com/acme/{repo}/tree/{branch:main}",
+	}
+	local := map[string]string{"wiki": "wiki.corp.example"}
+
+	tcs := []struct {
+		name        string
+		args        []string
+		want        []string
+		wantUnknown []string
+		wantErr     bool
+	}{
+		{"Test shortcuts and URLs", []string{"mail", "cal", "github.com/acme", "wiki"}, []string{"gmail.com", "calendar.google.com", "github.com/acme", "wiki.corp.example"}, nil, false},
+		{"Test templates take their arguments", []string{"jira", "PROJ-1", "mail", "jira", "PROJ-2"}, []string{"jira.corp.example/browse/PROJ-1", "gmail.com", "jira.corp.example/browse/PROJ-2"}, nil, false},
+		{"Test optional arguments stop at the next target", []string{"tree", "api", "mail"}, []string{"github.com/acme/api/tree/main", "gmail.com"}, nil, false},
+		{"Test optional arguments", []string{"tree", "api", "dev", "mail"}, []string{"github.com/acme/api/tree/dev", "gmail.com"}, nil, false},
+		{"Test required arguments are taken even if they are targets", []string{"jira", "mail"}, []string{"jira.corp.example/browse/mail"}, nil, false},
+		{"Test unknown targets are reported together", []string{"mial", "cal", "clandar"}, nil, []string{"mial", "clandar"}, false},
+		{"Test missing template arguments", []string{"jira"}, nil, nil, true},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			targets, unknown, err := ResolveTargets(tc.args, shortcuts, local)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tc.wantErr)
+			}
+			var got []string
+			for _, target := range targets {
+				got = append(got, target.URL)
+			}
+			if !reflect.DeepEqual(got, tc.want) || !reflect.DeepEqual(unknown, tc.wantUnknown) {
+				t.Errorf("got %q and unknown %q, want %q and %q", got, unknown, tc.want, tc.wantUnknown)
+			}
+		})
+	}
+}
+
+func TestSearchURL(t *testing.T) {
+	engines := map[string]string{
+		"google": "https://google.com/search?q={q}",
+		"gh":     "https://github.com/search?q={q}&type=repositories",
+	}
+
+	tcs := []struct {
+		engine  string
+		query   string
+		want    string
+		wantErr bool
+	}{
+		{"google", "c++ & go #1", "https://google.com/search?q=c%2B%2B+%26+go+%231", false},
+		{"google", "perché", "https://google.com/search?q=perch%C3%A9", false},
+		{"google", "!gh cobra", "https://github.com/search?q=cobra&type=repositories", false},
+		{"google", "!nope cobra", "", true},
+		{"bing", "cobra", "", true},
+	}
+
+	for _, tc := range tcs {
+		got, err := SearchURL(engines, tc.engine, tc.query)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("%s %q: got error %v, wantErr %v", tc.engine, tc.query, err, tc.wantErr)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("%s %q: got %v, want %v", tc.engine, tc.query, got, tc.want)
+		}
+	}
+}
+
+func TestDiscoverProfiles(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("LOCALAPPDATA", t.TempDir())
+	t.Setenv("APPDATA", t.TempDir())
+
+	chromeDir, _, err := profileDataDir("chrome")
+	if err != nil {
+		t.Skip(err)
+	}
+	writeFixture(t, filepath.Join(chromeDir, "Local State"), `{
+		"profile": {"info_cache": {
+			"Profile 1": {"name": "Work"},
+			"Default": {"name": "Person 1"}
+		}}
+	}`)
+
+	firefoxDir, _, _ := profileDataDir("firefox")
+	writeFixture(t, filepath.Join(firefoxDir, "profiles.ini"), `[General]
+StartWithLastProfile=1
+
+[Profile1]
+Name=work
+IsRelative=1
+Path=Profiles/abcd.work
+
+[Profile0]
+Name=default-release
+IsRelative=0
+Path=/opt/firefox/default
+Default=1
+
+[Install4F96D1932A9F858E]
+Default=Profiles/abcd.work
+`)
+
+	profiles, err := DiscoverProfiles("chrome")
+	if err != nil {
+		t.Fatalf("Error discovering chrome profiles: %v", err)
+	}
+	if len(profiles) != 2 || profiles[0].Dir != "Default" || profiles[1].Name != "Work" {
+		t.Errorf("got %+v", profiles)
+	}
+	if got := profiles[1].Profile().Name; got != "work" {
+		t.Errorf("got %v, want %v", got, "work")
+	}
+
+	profiles, err = DiscoverProfiles("firefox")
+	if err != nil {
+		t.Fatalf("Error discovering firefox profiles: %v", err)
+	}
+	want := []DiscoveredProfile{
+		{Browser: "firefox", Name: "default-release", Dir: filepath.FromSlash("/opt/firefox/default")},
+		{Browser: "firefox", Name: "work", Dir: filepath.Join(firefoxDir, "Profiles", "abcd.work")},
+	}
+	if len(profiles) != len(want) {
+		t.Fatalf("got %+v, want %+v", profiles, want)
+	}
+	for i := range want {
+		if profiles[i] != want[i] {
+			t.Errorf("got %+v, want %+v", profiles[i], want[i])
+		}
+	}
+}
+
+func writeFixture(t *testing.T, path, content string) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("Error creating fixture directory: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Error writing fixture: %v", err)
+	}
+}
+
+func TestOpenURLsPrivate(t *testing.T) {
+	UseBrowsers(map[string]config.Browser{
+		"fake": {Path: "/bin/true", Family: ChromiumFamily, IncognitoFlag: "--incognito"},
+	})
+	links := storage.NewMemoryStore(map[string]storage.Link{"https://go.dev": {Categories: []string{"go"}}})
+	history := storage.NewMemoryStore(map[string]time.Time{})
+	SetStores(storage.NewMemoryStore(map[string]string{}), links)
+	SetHistory(history)
+	t.Cleanup(func() { UseBrowsers(nil); SetStores(nil, nil); SetHistory(nil) })
+
+	if err := OpenURLs("fake", config.Profile{}, []string{"go.dev"}, LaunchOptions{Private: true}); err != nil {
+		t.Fatalf("Error opening privately: %v", err)
+	}
+	if got, _ := links.Get("https://go.dev"); got.OpenCount != 0 || !got.LastOpened.IsZero() {
+		t.Errorf("got %v opens at %v after a private open, want none", got.OpenCount, got.LastOpened)
+	}
+	if got := history.List(); len(got) != 0 {
+		t.Errorf("got history %v after a private open, want none", got)
+	}
+
+	if err := OpenURLs("fake", config.Profile{}, []string{"go.dev"}, LaunchOptions{}); err != nil {
+		t.Fatalf("Error opening: %v", err)
+	}
+	if got, _ := links.Get("https://go.dev"); got.OpenCount != 1 {
+		t.Errorf("got %v opens, want 1", got.OpenCount)
+	}
+	if got := history.List(); len(got) != 1 {
+		t.Errorf("got history %v, want go.dev", got)
+	}
+}
+
+func TestLookupBrowser(t *testing.T) {
+	UseBrowsers(map[string]config.Browser{
+		"firefox":   {Path: "/opt/firefox/firefox"},
+		"librewolf": {Candidates: []string{"librewolf"}, Family: GeckoFamily, IncognitoFlag: "--private-window"},
+		"surf":      {Path: "surf", Family: CustomFamily, ProfileFlag: "-c {profile}/cookies.txt"},
+	})
+	t.Cleanup(func() { UseBrowsers(nil) })
+
+	firefox, ok := LookupBrowser("firefox")
+	if !ok || firefox.Path != "/opt/firefox/firefox" {
+		t.Errorf("got %+v", firefox)
+	}
+
+	librewolf, ok := LookupBrowser("librewolf")
+	if !ok {
+		t.Fatalf("librewolf not found")
+	}
+	if librewolf.IncognitoFlag != "--private-window" || librewolf.NewWindowFlag != "-new-window" {
+		t.Errorf("got %+v", librewolf)
+	}
+	if got := expandFlag(librewolf.ProfileFlag, map[string]string{"profile": "/home/me/My Profile"}); len(got) != 2 || got[1] != "/home/me/My Profile" {
+		t.Errorf("got %q", got)
+	}
+
+	surf, _ := LookupBrowser("surf")
+	if surf.IncognitoFlag != "" {
+		t.Errorf("custom family should have no default flags, got %+v", surf)
+	}
+
+	if _, ok := LookupBrowser("netscape"); ok {
+		t.Errorf("got a definition for an unknown browser")
+	}
+}
+
+func TestLaunchArgs(t *testing.T) {
+	chromium := familyDefaults[ChromiumFamily]
+	gecko := familyDefaults[GeckoFamily]
+	profile := config.Profile{ProfileDir: "Work"}
+	one := []string{"https://app.slack.com"}
+
+	tcs := []struct {
+		name    string
+		browser config.Browser
+		urls    []string
+		opts    LaunchOptions
+		want    []string
+		wantErr bool
+	}{
+		{"Test chromium private new window", chromium, one, LaunchOptions{Private: true, NewWindow: true}, []string{"--profile-directory=Work", "--incognito", "--new-window", "https://app.slack.com"}, false},
+		{"Test chromium app with window size", chromium, one, LaunchOptions{App: true, WindowSize: "1280x800"}, []string{"--profile-directory=Work", "--window-size=1280,800", "--app=https://app.slack.com"}, false},
+		{"Test chromium kiosk", chromium, one, LaunchOptions{Kiosk: true}, []string{"--profile-directory=Work", "--kiosk", "https://app.slack.com"}, false},
+		{"Test gecko private kiosk", gecko, one, LaunchOptions{Private: true, Kiosk: true}, []string{"-profile", "Work", "-private-window", "-kiosk", "https://app.slack.com"}, false},
+		{"Test gecko has no app mode", gecko, one, LaunchOptions{App: true}, nil, true},
+		{"Test gecko has no window size", gecko, one, LaunchOptions{WindowSize: "800x600"}, nil, true},
+		{"Test app opens one URL", chromium, []string{"https://a.com", "https://b.com"}, LaunchOptions{App: true}, nil, true},
+		{"Test app and new window", chromium, one, LaunchOptions{App: true, NewWindow: true}, nil, true},
+		{"Test kiosk and window size", chromium, one, LaunchOptions{Kiosk: true, WindowSize: "800x600"}, nil, true},
+		{"Test invalid window size", chromium, one, LaunchOptions{WindowSize: "big"}, nil, true},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := launchArgs("test", tc.browser, profile, tc.urls, tc.opts)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tc.wantErr)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+
+	t.Run("Test shortcut modes add to the flags", func(t *testing.T) {
+		got := LaunchOptions{NewWindow: true, WindowSize: "800x600"}.WithMode(config.LaunchMode{App: true, WindowSize: "1280x800"})
+		if want := (LaunchOptions{NewWindow: true, App: true, WindowSize: "800x600"}); got != want {
+			t.Errorf("got %+v, want %+v", got, want)
+		}
+	})
+}
+
+func TestMatchGlob(t *testing.T) {
+	tcs := []struct {
+		pattern string
+		s       string
+		want    bool
+	}{
+		{"https://go.dev", "https://go.dev", true},
+		{"https://go.dev", "https://go.dev/doc", false},
+		{"*go.dev*", "https://go.dev/doc", true},
+		{"https://github.com/*/go", "https://github.com/golang/go", true},
+		{"https://github.com/*/go", "https://github.com/golang/tools", false},
+		{"http?://example.com", "https://example.com", true},
+		{"http?://example.com", "http://example.com", false},
+		{"*.example.com", "https://docs.example.com", true},
+		{"*.example.com", "https://docsXexample.com", false},
+	}
+	for _, tc := range tcs {
+		if got := MatchGlob(tc.pattern, tc.s); got != tc.want {
+			t.Errorf("MatchGlob(%q, %q) = %v, want %v", tc.pattern, tc.s, got, tc.want)
+		}
+	}
+}
+
+func TestRouteURL(t *testing.T) {
+	var cnf config.Config
+	err := yaml.Unmarshal([]byte(`
+routes:
+  - "*.atlassian.net -> work"
+  - match: "regex:^https://github.com/acme/"
+    profile: work
+  - match: "github.com/*"
+    profile: personal
+`), &cnf)
+	if err != nil {
+		t.Fatalf("Error parsing routes: %v", err)
+	}
+
+	tcs := []struct {
+		url      string
+		fallback string
+		profile  string
+		route    int
+		wantErr  bool
+	}{
+		{"https://acme.atlassian.net/browse/PROJ-1", "", "work", 0, false},
+		{"acme.atlassian.net", "", "work", 0, false},
+		{"https://github.com/acme/api", "", "work", 1, false},
+		{"github.com/golang/go", "", "personal", 2, false},
+		{"https://example.com", "personal", "personal", -1, false},
+		{"https://example.com", "", "", 0, true},
+	}
+
+	for _, tc := range tcs {
+		match, err := RouteURL(cnf.Routes, tc.fallback, tc.url)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("%s: got error %v, wantErr %v", tc.url, err, tc.wantErr)
+			continue
+		}
+		if !tc.wantErr && (match.Profile != tc.profile || match.Route != tc.route) {
+			t.Errorf("%s: got %+v, want profile %v and route %v", tc.url, match, tc.profile, tc.route)
+		}
+	}
+}
+
+func TestWriteListing(t *testing.T) {
+	shortcuts := ShortcutListing(
+		map[string]string{"mail": "gmail.com", "gh": "github.com"},
+		map[string]string{"gh": "github.com/acme", "cal": "calendar.google.com"},
+	)
+
+	t.Run("Test shortcuts are sorted with their source", func(t *testing.T) {
+		var got []string
+		for _, e := range shortcuts {
+			got = append(got, e.Name+":"+e.Source)
+		}
+		want := "cal:local gh:config gh:local mail:config"
+		if strings.Join(got, " ") != want {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	})
+
+	t.Run("Test tsv", func(t *testing.T) {
+		var buf bytes.Buffer
+		links := LinkListing(map[string]storage.Link{
+			"https://go.dev": {Categories: []string{"go", "docs"}, Title: "Go", OpenCount: 3},
+			"https://a.dev":  {},
+		})
+		if err := WriteListing(&buf, OutputTSV, links); err != nil {
+			t.Fatalf("Error writing listing: %v", err)
+		}
+		want := "URL\tTITLE\tCATEGORIES\tOPENED\tSOURCE\nhttps://a.dev\t\t\t0\tlocal\nhttps://go.dev\tGo\tgo,docs\t3\tlocal\n"
+		if buf.String() != want {
+			t.Errorf("got %q, want %q", buf.String(), want)
+		}
+	})
+
+	t.Run("Test json", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := WriteListing(&buf, OutputJSON, shortcuts); err != nil {
+			t.Fatalf("Error writing listing: %v", err)
+		}
+		var got []ShortcutEntry
+		if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+			t.Fatalf("Error parsing json: %v", err)
+		}
+		if len(got) != 4 || got[0] != shortcuts[0] {
+			t.Errorf("got %v, want %v", got, shortcuts)
+		}
+	})
+
+	t.Run("Test unknown format", func(t *testing.T) {
+		if err := WriteListing(&bytes.Buffer{}, "xml", shortcuts); err == nil {
+			t.Errorf("got no error for an unknown format")
+		}
+	})
+}
+
+func TestSearchLinks(t *testing.T) {
+	links := map[string]storage.Link{
+		"https://go.dev/doc/effective_go": {Categories: []string{"go", "docs"}},
+		"https://github.com/golang/go":    {Categories: []string{"go", "code"}},
+		"https://news.ycombinator.com":    {Categories: []string{"news"}, Title: "Hacker News"},
+		"https://docs.python.org":         {Categories: []string{"python", "docs"}},
+	}
+
+	tcs := []struct {
+		name  string
+		query LinkQuery
+		want  []string
+	}{
+		{"Test substring in url", LinkQuery{Terms: []string{"GO.dev"}}, []string{"https://go.dev/doc/effective_go"}},
+		{"Test substring in categories", LinkQuery{Terms: []string{"news"}}, []string{"https://news.ycombinator.com"}},
+		{"Test substring in title", LinkQuery{Terms: []string{"hacker"}}, []string{"https://news.ycombinator.com"}},
+		{"Test every term has to match", LinkQuery{Terms: []string{"docs", "python"}}, []string{"https://docs.python.org"}},
+		{"Test categories all present", LinkQuery{Categories: []string{"go", "docs"}}, []string{"https://go.dev/doc/effective_go"}},
+		{"Test any category", LinkQuery{Categories: []string{"code", "news"}, AnyCategory: true}, []string{"https://github.com/golang/go", "https://news.ycombinator.com"}},
+		{"Test fuzzy ranking", LinkQuery{Terms: []string{"ycomb"}, Fuzzy: true}, []string{"https://news.ycombinator.com"}},
+		{"Test fuzzy subsequence", LinkQuery{Terms: []string{"gthb"}, Fuzzy: true}, []string{"https://github.com/golang/go"}},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			var got []string
+			for _, m := range SearchLinks(links, tc.query) {
+				got = append(got, m.URL)
+			}
+			if strings.Join(got, " ") != strings.Join(tc.want, " ") {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+
+	t.Run("Test fuzzy prefers consecutive characters", func(t *testing.T) {
+		tight, _ := FuzzyScore("doc", "go.dev/doc")
+		loose, _ := FuzzyScore("doc", "dev/ops/ci")
+		if tight <= loose {
+			t.Errorf("got %v for a consecutive match and %v for a scattered one", tight, loose)
+		}
+	})
+}
+
+func TestReadBookmarks(t *testing.T) {
+	dir := t.TempDir()
+
+	t.Run("Test netscape html", func(t *testing.T) {
+		path := filepath.Join(dir, "bookmarks.html")
+		writeFixture(t, path, `<!DOCTYPE NETSCAPE-Bookmark-file-1>
+<META HTTP-EQUIV="Content-Type" CONTENT="text/html; charset=UTF-8">
+<TITLE>Bookmarks</TITLE>
+<H1>Bookmarks</H1>
+<DL><p>
+    <DT><H3 ADD_DATE="1700000000" PERSONAL_TOOLBAR_FOLDER="true">Bookmarks bar</H3>
+    <DL><p>
+        <DT><A HREF="https://go.dev/" ADD_DATE="1700000001">Go</A>
+        <DT><H3>Work</H3>
+        <DL><p>
+            <DT><H3>Docs</H3>
+            <DL><p>
+                <DT><A HREF="https://pkg.go.dev/">Packages</A>
+            </DL><p>
+        </DL><p>
+    </DL><p>
+    <DT><A HREF="javascript:alert(1)">Bookmarklet</A>
+</DL><p>
+`)
+		got, err := ReadBookmarks(path, "")
+		if err != nil {
+			t.Fatalf("Error reading bookmarks: %v", err)
+		}
+		want := []Bookmark{
+			{URL: "https://go.dev/", Title: "Go", Added: time.Unix(1700000001, 0)},
+			{URL: "https://pkg.go.dev/", Title: "Packages", Folders: []string{"Work", "Docs"}},
+			{URL: "javascript:alert(1)", Title: "Bookmarklet"},
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %+v, want %+v", got, want)
+		}
+	})
+
+	t.Run("Test chromium json", func(t *testing.T) {
+		path := filepath.Join(dir, "Bookmarks")
+		writeFixture(t, path, `{
+   "checksum": "x",
+   "roots": {
+      "bookmark_bar": {"type": "folder", "name": "Bookmarks bar", "children": [
+         {"type": "url", "name": "Go", "url": "https://go.dev/", "date_added": "13340000000000000"},
+         {"type": "folder", "name": "Work", "children": [
+            {"type": "url", "name": "Jira", "url": "https://acme.atlassian.net/"}
+         ]}
+      ]},
+      "other": {"type": "folder", "name": "Other bookmarks", "children": []}
+   },
+   "version": 1
+}`)
+		got, err := ReadBookmarks(path, "")
+		if err != nil {
+			t.Fatalf("Error reading bookmarks: %v", err)
+		}
+		if len(got) != 2 || got[0].URL != "https://go.dev/" || got[0].Added.Year() != 2023 || len(got[0].Folders) != 0 {
+			t.Fatalf("got %+v", got)
+		}
+		if !reflect.DeepEqual(got[1].Folders, []string{"Work"}) {
+			t.Errorf("got folders %v, want %v", got[1].Folders, []string{"Work"})
+		}
+	})
+
+	t.Run("Test firefox places.sqlite", func(t *testing.T) {
+		if _, err := exec.LookPath("sqlite3"); err != nil {
+			t.Skip("sqlite3 is not installed")
+		}
+		path := filepath.Join(dir, "places.sqlite")
+		schema := `
+CREATE TABLE moz_places (id INTEGER PRIMARY KEY, url TEXT);
+CREATE TABLE moz_bookmarks (id INTEGER PRIMARY KEY, type INTEGER, fk INTEGER, parent INTEGER, position INTEGER, title TEXT, dateAdded INTEGER, guid TEXT);
+INSERT INTO moz_places VALUES (1, 'https://go.dev/'), (2, 'place:sort=8');
+INSERT INTO moz_bookmarks VALUES
+  (1, 2, NULL, 0, 0, '', 0, 'root________'),
+  (2, 2, NULL, 1, 0, 'menu', 0, 'menu________'),
+  (4, 2, NULL, 1, 1, 'tags', 0, 'tags________'),
+  (10, 2, NULL, 2, 0, 'Dev', 0, 'dev'),
+  (11, 1, 1, 10, 0, 'Go', 1700000000000000, 'go'),
+  (12, 2, NULL, 4, 0, 'golang', 0, 'golangtag'),
+  (13, 1, 1, 12, 0, NULL, 0, 'gotag'),
+  (14, 1, 2, 2, 1, 'Recent', 0, 'recent');`
+		if out, err := exec.Command("sqlite3", path, schema).CombinedOutput(); err != nil {
+			t.Fatalf("Error creating places.sqlite: %v %s", err, out)
+		}
+
+		got, err := ReadBookmarks(path, "")
+		if err != nil {
+			t.Fatalf("Error reading bookmarks: %v", err)
+		}
+		want := []Bookmark{
+			{URL: "place:sort=8", Title: "Recent"},
+			{URL: "https://go.dev/", Title: "Go", Folders: []string{"Dev", "golang"}, Added: time.UnixMicro(1700000000000000)},
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %+v, want %+v", got, want)
+		}
+	})
+
+	t.Run("Test unknown format", func(t *testing.T) {
+		path := filepath.Join(dir, "notes.txt")
+		writeFixture(t, path, "just some notes")
+		if _, err := ReadBookmarks(path, ""); err == nil {
+			t.Errorf("got no error for a text file")
+		}
+	})
+}
+
+func TestExportLinks(t *testing.T) {
+	links := map[string]storage.Link{
+		"https://go.dev":               {Categories: []string{"go", "docs"}, Title: "Go & friends", Note: "start here"},
+		"https://github.com/golang/go": {Categories: []string{"go", "code"}},
+		"https://example.com":          {},
+	}
+
+	t.Run("Test html can be imported back", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := ExportLinks(&buf, ExportHTML, links, nil); err != nil {
+			t.Fatalf("Error exporting links: %v", err)
+		}
+		bookmarks, err := ReadNetscapeBookmarks(&buf)
+		if err != nil {
+			t.Fatalf("Error reading exported links: %v", err)
+		}
+
+		got := make(map[string]string)
+		for _, b := range bookmarks {
+			got[b.URL] = b.Title + "|" + strings.Join(b.Folders, "/")
+		}
+		want := map[string]string{
+			"https://example.com":          "https://example.com|",
+			"https://github.com/golang/go": "https://github.com/golang/go|go",
+			"https://go.dev":               "Go & friends|go",
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	})
+
+	t.Run("Test markdown groups by category", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := ExportLinks(&buf, ExportMarkdown, links, []string{"go"}); err != nil {
+			t.Fatalf("Error exporting links: %v", err)
+		}
+		want := "# Links\n\n## go\n\n- [https://github.com/golang/go](https://github.com/golang/go)\n- [Go & friends](https://go.dev) - start here\n"
+		if buf.String() != want {
+			t.Errorf("got %q, want %q", buf.String(), want)
+		}
+	})
+
+	t.Run("Test csv", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := ExportLinks(&buf, ExportCSV, links, nil); err != nil {
+			t.Fatalf("Error exporting links: %v", err)
+		}
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		if len(lines) != 4 || lines[3] != `https://go.dev,Go & friends,"go,docs",start here,,,,0` {
+			t.Errorf("got %q", lines)
+		}
+	})
+}
+
+func TestCheckLinks(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("/no-head", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
+	mux.Handle("/moved", http.RedirectHandler("/ok", http.StatusMovedPermanently))
+	mux.Handle("/temporary", http.RedirectHandler("/ok", http.StatusFound))
+	mux.Handle("/gone", http.NotFoundHandler())
+	mux.HandleFunc("/forbidden", func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusForbidden) })
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+		}
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	paths := []string{"/ok", "/no-head", "/moved", "/temporary", "/gone", "/slow", "/forbidden"}
+	var urls []string
+	for _, p := range paths {
+		urls = append(urls, server.URL+p)
+	}
+	// Nothing listens there any more
+	closed := httptest.NewServer(mux)
+	closed.Close()
+	urls = append(urls, closed.URL+"/ok")
+	paths = append(paths, "closed")
+
+	var reported int
+	results := CheckLinks(urls, CheckOptions{
+		Workers:  3,
+		Timeout:  200 * time.Millisecond,
+		Client:   server.Client(),
+		OnResult: func(LinkStatus) { reported++ },
+	})
+	if reported != len(urls) {
+		t.Errorf("got %v results reported, want %v", reported, len(urls))
+	}
+
+	tcs := []struct {
+		status int
+		moved  bool
+		dead   bool
+		failed bool
+	}{
+		{200, false, false, false},
+		{200, false, false, false},
+		{200, true, false, false},
+		{200, false, false, false},
+		{404, false, true, false},
+		{0, false, false, true},
+		{403, false, false, true},
+		{0, false, false, true},
+	}
+	for i, tc := range tcs {
+		r := results[i]
+		if r.URL != urls[i] || r.Status != tc.status || r.Moved != tc.moved || r.Dead() != tc.dead || r.Failed() != tc.failed {
+			t.Errorf("%s: got status %v, moved %v, dead %v, failed %v (%v), want %v, %v, %v, %v",
+				paths[i], r.Status, r.Moved, r.Dead(), r.Failed(), r.Err, tc.status, tc.moved, tc.dead, tc.failed)
+		}
+	}
+	if results[2].FinalURL != server.URL+"/ok" {
+		t.Errorf("got final URL %v, want %v", results[2].FinalURL, server.URL+"/ok")
+	}
+	if results[0].FinalURL != "" {
+		t.Errorf("got final URL %v without redirects, want none", results[0].FinalURL)
+	}
+
+	t.Run("Test unknown host", func(t *testing.T) {
+		lookupErr := &url.Error{Op: "Head", URL: "https://nope.invalid", Err: &net.OpError{Op: "dial", Err: &net.DNSError{Err: "no such host", Name: "nope.invalid", IsNotFound: true}}}
+		if r := (LinkStatus{Err: lookupErr}); !r.Dead() || r.Failed() {
+			t.Errorf("got dead %v, failed %v for an unknown host, want dead", r.Dead(), r.Failed())
+		}
+		timeoutErr := &url.Error{Op: "Head", URL: "https://example.com", Err: &net.DNSError{Err: "i/o timeout", Name: "example.com", IsTimeout: true}}
+		if r := (LinkStatus{Err: timeoutErr}); r.Dead() || !r.Failed() {
+			t.Errorf("got dead %v, failed %v for a lookup timeout, want failed", r.Dead(), r.Failed())
+		}
+	})
+}
+
+func TestFetchPreview(t *testing.T) {
+	page := `<!DOCTYPE html>
+<html lang="fr">
+<head>
+<meta charset="iso-8859-1">
+<title>Caf` + "\xe9" + ` ` + "\x93" + `cr` + "\xe8" + `me` + "\x94" + `</title>
+<meta name="description" content="Une page">
+<meta property="og:title" content="OG title">
+<meta property="og:image" content="https://cdn.example.com/a.png">
+<meta name="twitter:card" content="summary">
+<link rel="canonical" href="/article">
+<link rel="shortcut icon" href="static/icon.png">
+<script type="application/ld+json">{"@context": "https://schema.org", "@graph": [{"@type": "WebPage"}, {"@type": "Article", "datePublished": "2024-05-01T10:00:00Z"}]}</script>
+</head>
+<body>
+<nav>one two three</nav>
+<h1>First</h1><h1> Second
+  heading </h1>
+<script>var ignored = "words";</script>
+</body>
+</html>`
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/posts/page", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(page))
+	})
+	mux.HandleFunc("/bare", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(`<html><head><meta property="og:title" content="Only OG"></head><body>` + strings.Repeat("word ", 450) + `</body></html>`))
+	})
+	mux.HandleFunc("/koi8", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=koi8-r")
+		w.Write([]byte(`<html><head><title>Plain ASCII</title></head></html>`))
+	})
+	mux.Handle("/gone", http.NotFoundHandler())
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	t.Run("Test full page", func(t *testing.T) {
+		preview, err := FetchPreview(server.URL+"/posts/page", time.Second)
+		if err != nil {
+			t.Fatalf("got error %v", err)
+		}
+		want := Preview{
+			URL:         server.URL + "/posts/page",
+			Title:       "Café “crème”",
+			Description: "Une page",
+			H1:          []string{"First", "Second heading"},
+			Canonical:   server.URL + "/article",
+			Favicon:     server.URL + "/posts/static/icon.png",
+			Lang:        "fr",
+			Published:   "2024-05-01T10:00:00Z",
+			OpenGraph:   map[string]string{"og:title": "OG title", "og:image": "https://cdn.example.com/a.png"},
+			Twitter:     map[string]string{"twitter:card": "summary"},
+			Words:       3,
+			ReadingTime: 1,
+		}
+		if !reflect.DeepEqual(preview, want) {
+			t.Errorf("got %+v, want %+v", preview, want)
+		}
+	})
+
+	t.Run("Test fallbacks", func(t *testing.T) {
+		preview, err := FetchPreview(server.URL+"/bare", time.Second)
+		if err != nil {
+			t.Fatalf("got error %v", err)
+		}
+		if preview.Title != "Only OG" || preview.Favicon != server.URL+"/favicon.ico" || preview.Words != 450 || preview.ReadingTime != 3 {
+			t.Errorf("got title %q, favicon %q, %d words, %d min", preview.Title, preview.Favicon, preview.Words, preview.ReadingTime)
+		}
+	})
+
+	t.Run("Test unsupported charset", func(t *testing.T) {
+		preview, err := FetchPreview(server.URL+"/koi8", time.Second)
+		if err != nil || preview.Title != "Plain ASCII" {
+			t.Errorf("got title %q and error %v, want the page read as it is", preview.Title, err)
+		}
+	})
+
+	t.Run("Test errors", func(t *testing.T) {
+		if _, err := FetchPreview(server.URL+"/gone", time.Second); err == nil {
+			t.Errorf("got no error for a missing page")
+		}
+	})
+}
+
+func TestPreviewCache(t *testing.T) {
+	var requests, downloads int
+	title := "First"
+	mux := http.NewServeMux()
+	mux.HandleFunc("/page", func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		etag := `"` + title + `"`
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		downloads++
+		w.Header().Set("ETag", etag)
+		w.Write([]byte("<title>" + title + "</title>"))
+	})
+	server := httptest.NewServer(mux)
+
+	now := time.Now()
+	cache := NewPreviewCache(filepath.Join(t.TempDir(), "cache", "previews.json"), time.Hour)
+	cache.now = func() time.Time { return now }
+	link := server.URL + "/page"
+
+	fetch := func(link string, refresh bool, wantTitle string, wantRequests, wantDownloads int) {
+		t.Helper()
+		preview, err := cache.Fetch(link, time.Second, refresh)
+		if err != nil {
+			t.Fatalf("got error %v", err)
+		}
+		if preview.Title != wantTitle || requests != wantRequests || downloads != wantDownloads {
+			t.Errorf("got %q after %d requests and %d downloads, want %q, %d and %d", preview.Title, requests, downloads, wantTitle, wantRequests, wantDownloads)
+		}
+	}
+
+	t.Run("Test fresh entries are used as they are", func(t *testing.T) {
+		fetch(link, false, "First", 1, 1)
+		fetch(link+"/#top", false, "First", 1, 1)
+	})
+
+	t.Run("Test stale entries are revalidated", func(t *testing.T) {
+		now = now.Add(2 * time.Hour)
+		fetch(link, false, "First", 2, 1)
+		fetch(link, false, "First", 2, 1)
+	})
+
+	t.Run("Test refresh downloads the page again", func(t *testing.T) {
+		title = "Second"
+		fetch(link, true, "Second", 3, 2)
+	})
+
+	t.Run("Test titles are known offline", func(t *testing.T) {
+		server.Close()
+		now = now.Add(2 * time.Hour)
+		fetch(link, false, "Second", 3, 2)
+
+		links := map[string]storage.Link{
+			link:                     {Categories: []string{"test"}},
+			"https://example.com/x/": {Title: "Mine"},
+		}
+		cache.FillTitles(links)
+		if links[link].Title != "Second" || links["https://example.com/x/"].Title != "Mine" {
+			t.Errorf("got titles %q and %q", links[link].Title, links["https://example.com/x/"].Title)
+		}
+	})
+
+	t.Run("Test stats and clear", func(t *testing.T) {
+		if stats := cache.Stats(); stats.Entries != 1 || stats.Fresh != 0 || stats.Size == 0 {
+			t.Errorf("got %+v, want 1 stale entry", stats)
+		}
+		if entries, err := cache.Clear(); err != nil || entries != 1 {
+			t.Errorf("got %v, %v, want 1 entry removed", entries, err)
+		}
+		if _, ok := cache.Lookup(link); ok {
+			t.Errorf("got a cached preview after clearing the cache")
+		}
+	})
+}
+
+func TestNormalizeURL(t *testing.T) {
+	tcs := []struct {
+		link string
+		want string
+	}{
+		{"example.com", "https://example.com"},
+		{"HTTPS://Example.COM:443/Path/", "https://example.com/Path"},
+		{"http://example.com:80/?q=1#frag", "http://example.com?q=1"},
+		{"http://example.com:8080/", "http://example.com:8080"},
+	}
+	for _, tc := range tcs {
+		if got := NormalizeURL(tc.link); got != tc.want {
+			t.Errorf("%s: got %v, want %v", tc.link, got, tc.want)
+		}
+	}
+}
+
+func TestExtractURLs(t *testing.T) {
+	text := `# reading list
+https://go.dev/doc/effective_go
+<https://pkg.go.dev/net/http>, see (https://go.dev/blog).
+ftp://example.com/file not-a-url example.com
+https://GO.dev/doc/effective_go/ http:// "https://github.com/acme"`
+	want := []string{"https://go.dev/doc/effective_go", "https://pkg.go.dev/net/http", "https://go.dev/blog", "https://github.com/acme"}
+	if got := ExtractURLs(text); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestSystemClipboard(t *testing.T) {
+	bin := t.TempDir()
+	t.Setenv("PATH", bin)
+	t.Setenv("WAYLAND_DISPLAY", "")
+
+	if _, err := (systemClipboard{}).ReadClipboard(); err == nil {
+		t.Errorf("got no error without a clipboard tool")
+	}
+
+	script := "#!/bin/sh\necho \"$@\"\n"
+	if err := os.WriteFile(filepath.Join(bin, "xsel"), []byte(script), 0755); err != nil {
+		t.Fatalf("Error writing xsel: %v", err)
+	}
+	got, err := (systemClipboard{}).ReadClipboard()
+	if err != nil || got != "--clipboard --output\n" {
+		t.Errorf("got %q, %v, want the xsel arguments", got, err)
+	}
+
+	// Only shell builtins, PATH holding nothing else
+	script = "#!/bin/sh\nread -r line\nprintf %s \"$line\" > " + filepath.Join(bin, "copied") + "\n"
+	if err := os.WriteFile(filepath.Join(bin, "xclip"), []byte(script), 0755); err != nil {
+		t.Fatalf("Error writing xclip: %v", err)
+	}
+	if err := (systemClipboard{}).WriteClipboard("https://example.com"); err != nil {
+		t.Fatalf("got error %v", err)
+	}
+	if copied, _ := os.ReadFile(filepath.Join(bin, "copied")); string(copied) != "https://example.com" {
+		t.Errorf("got %q copied with xclip", copied)
+	}
+
+	fake := &FakeClipboard{}
+	UseClipboard(fake)
+	t.Cleanup(func() { UseClipboard(nil) })
+	WriteClipboard("https://example.com")
+	if got, _ := ReadClipboard(); got != "https://example.com" {
+		t.Errorf("got %q from the fake clipboard", got)
+	}
+}